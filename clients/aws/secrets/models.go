@@ -8,6 +8,8 @@ type Secret struct {
 	Name                string `json:"dbname"`
 	Engine              string `json:"engine"`
 	DBClusterIdentifier string `json:"dbInstanceIdentifier"`
+	// VersionID is the identifier of the secret version the values were read from.
+	VersionID string `json:"-"`
 }
 
 type AzureAPISecret struct {
@@ -44,31 +44,38 @@ func New(ctx context.Context, region string) (Client, error) {
 
 func (c *secretsClient) GetSecret(ctx context.Context, secretID string) (*Secret, error) {
 	secret := &Secret{}
-	err := c.GetValue(ctx, secretID, secret)
+	versionID, err := c.getValue(ctx, secretID, secret)
 	if err != nil {
 		return nil, err
 	}
+	secret.VersionID = versionID
 	return secret, nil
 }
 
 func (c *secretsClient) GetValue(ctx context.Context, secretID string, target interface{}) error {
+	_, err := c.getValue(ctx, secretID, target)
+	return err
+}
+
+// getValue unmarshals the current secret value into target and returns its version identifier.
+func (c *secretsClient) getValue(ctx context.Context, secretID string, target interface{}) (string, error) {
 	if len(secretID) == 0 {
-		return errors.New("secrets: secretId cannot be empty")
+		return "", errors.New("secrets: secretId cannot be empty")
 	}
 	params := &secretsmanager.GetSecretValueInput{
 		SecretId: awsv2.String(secretID),
 	}
 	result, err := c.GetSecretValue(ctx, params)
 	if err != nil {
-		return errors.Wrap(err, "secrets: failed GetSecretValue")
+		return "", errors.Wrap(err, "secrets: failed GetSecretValue")
 	}
 	if result == nil {
-		return errors.Wrap(err, "secrets: secret value is nil")
+		return "", errors.Wrap(err, "secrets: secret value is nil")
 	}
 	if err := json.Unmarshal([]byte(*result.SecretString), target); err != nil {
-		return errors.Wrap(err, "secrets: failed to unmarshal a secret value")
+		return "", errors.Wrap(err, "secrets: failed to unmarshal a secret value")
 	}
-	return nil
+	return awsv2.ToString(result.VersionId), nil
 }
 
 func (c *secretsClient) GetAzureSecret(ctx context.Context, secretID string) (*AzureAPISecret, error) {
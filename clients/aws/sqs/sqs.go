@@ -20,6 +20,8 @@ type Client interface {
 	DeleteMessage(ctx context.Context, message types.Message) error
 	// SendMessage sends message the specified queue
 	SendMessage(ctx context.Context, message *awssqs.SendMessageInput) (*awssqs.SendMessageOutput, error)
+	// Ping checks the queue is reachable by reading its attributes.
+	Ping(ctx context.Context) error
 }
 
 // sqsClient is a wrapper for the aws sqs client.
@@ -79,6 +81,14 @@ func (c *sqsClient) DeleteMessage(ctx context.Context, message types.Message) er
 	return err
 }
 
+func (c *sqsClient) Ping(ctx context.Context) error {
+	_, err := c.Client.GetQueueAttributes(ctx, &awssqs.GetQueueAttributesInput{
+		QueueUrl:       awsv2.String(c.QueueURL),
+		AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameQueueArn},
+	})
+	return err
+}
+
 func (c *sqsClient) SendMessage(ctx context.Context, params *awssqs.SendMessageInput) (*awssqs.SendMessageOutput, error) {
 	return c.Client.SendMessage(ctx, params)
 }
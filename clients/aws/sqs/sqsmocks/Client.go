@@ -30,6 +30,20 @@ func (_m *Client) DeleteMessage(ctx context.Context, message types.Message) erro
 	return r0
 }
 
+// Ping provides a mock function with given fields: ctx
+func (_m *Client) Ping(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ReceiveMessages provides a mock function with given fields: ctx, maxReceivedMessages
 func (_m *Client) ReceiveMessages(ctx context.Context, maxReceivedMessages int) (*servicesqs.ReceiveMessageOutput, error) {
 	ret := _m.Called(ctx, maxReceivedMessages)
@@ -0,0 +1,192 @@
+// Command migrate runs and inspects the database migrations of db/migrate outside the service
+// startup. The credentials are resolved as db.New does, from the connection flags or from the
+// AWS secret.
+//
+// Usage:
+//
+//	migrate [flags] up
+//	migrate [flags] down N
+//	migrate [flags] goto VERSION
+//	migrate [flags] status
+//	migrate [flags] force VERSION
+//	migrate [flags] create NAME
+//	migrate [flags] validate
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mauricetjmurphy/ms-common/db"
+	"github.com/mauricetjmurphy/ms-common/db/dialect"
+	"github.com/mauricetjmurphy/ms-common/db/dialect/postgres"
+	"github.com/mauricetjmurphy/ms-common/db/migrate"
+)
+
+const usage = `Usage: migrate [flags] COMMAND [ARG]
+
+Commands:
+  up               apply all the pending migrations
+  down N           revert the N last applied migrations
+  goto VERSION     migrate up or down to VERSION
+  status           print the current version and the applied and pending migrations
+  force VERSION    set VERSION without migrating and clear the dirty flag, -1 removes the version
+  create NAME      create the up and down files of a new migration in the source directory
+  validate         check the source for duplicate versions, gaps and missing up migrations
+
+Flags:
+`
+
+// dialects are the supported dialects, sqlite being registered by the cgo builds only.
+var dialects = map[string]func() dialect.Dialect{
+	"mysql":    dialect.MySQL,
+	"postgres": postgres.Dialect,
+}
+
+var dirtyPolicies = map[string]migrate.DirtyPolicy{
+	"fail":   migrate.DirtyFail,
+	"retry":  migrate.DirtyRetry,
+	"accept": migrate.DirtyAccept,
+}
+
+func main() {
+	if err := run(context.Background(), os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	var (
+		dialectName = fs.String("dialect", "mysql", "database dialect: mysql, postgres or sqlite, sqlite requiring a cgo build")
+		host        = fs.String("host", db.DefaultHost, "database host")
+		port        = fs.Int("port", 0, "database port, defaults to the dialect port")
+		name        = fs.String("name", "", "database name")
+		user        = fs.String("user", "", "database user")
+		password    = fs.String("password", "", "database password, defaults to $DB_PASSWORD")
+		region      = fs.String("aws-region", os.Getenv("AWS_REGION"), "AWS region of the secret")
+		secretID    = fs.String("aws-secret-id", "", "AWS secret ID holding the database connection, overrides the connection flags")
+		sourceURL   = fs.String("source", "file://migrations", "migrations source URL")
+		dirty       = fs.String("dirty", "fail", "dirty version policy: fail, retry or accept")
+		dryRun      = fs.Bool("dry-run", false, "log the planned migrations without applying them")
+		seq         = fs.Bool("seq", false, "create the migration with the next sequential version instead of a timestamp")
+	)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *password == "" {
+		*password = os.Getenv("DB_PASSWORD")
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("migrate : missing command")
+	}
+	command, arg := fs.Arg(0), fs.Arg(1)
+
+	// create and validate only read the source directory.
+	dir := strings.TrimPrefix(*sourceURL, "file://")
+	switch command {
+	case "create":
+		up, down, err := migrate.Create(dir, strings.Join(fs.Args()[1:], " "), *seq, time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Println(up)
+		fmt.Println(down)
+		return nil
+	case "validate":
+		if err := migrate.Validate(os.DirFS(dir), "."); err != nil {
+			return err
+		}
+		fmt.Println("migrations are valid")
+		return nil
+	}
+
+	newDialect, ok := dialects[*dialectName]
+	if !ok {
+		return fmt.Errorf("migrate : unknown dialect %v", *dialectName)
+	}
+	policy, ok := dirtyPolicies[*dirty]
+	if !ok {
+		return fmt.Errorf("migrate : unknown dirty policy %v", *dirty)
+	}
+	mi, err := db.NewMigrator(ctx,
+		db.Dialect(newDialect()),
+		db.Host(*host),
+		db.Port(*port),
+		db.Name(*name),
+		db.User(*user),
+		db.Password(*password),
+		db.AWSRegion(*region),
+		db.AWSSecretID(*secretID),
+		db.MigrationSourceURL(*sourceURL),
+		db.MigrationDirtyPolicy(policy),
+		db.MigrationDryRun(*dryRun),
+	)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		return printSteps(mi.Up())
+	case "down":
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("migrate : invalid number of migrations %q", arg)
+		}
+		return printSteps(mi.Down(n))
+	case "goto":
+		version, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("migrate : invalid version %q", arg)
+		}
+		return printSteps(mi.Goto(uint(version)))
+	case "force":
+		version, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("migrate : invalid version %q", arg)
+		}
+		return mi.Force(version)
+	case "status":
+		status, err := mi.Status()
+		if err != nil {
+			return err
+		}
+		printStatus(status)
+		return nil
+	}
+	fs.Usage()
+	return fmt.Errorf("migrate : unknown command %v", command)
+}
+
+// printSteps prints the planned steps of a migration.
+func printSteps(steps []migrate.Step, err error) error {
+	if err != nil {
+		return err
+	}
+	for _, step := range steps {
+		fmt.Println(step)
+	}
+	return nil
+}
+
+func printStatus(status *migrate.Status) {
+	fmt.Printf("version: %v dirty: %v\n", status.Version, status.Dirty)
+	for _, m := range status.Applied {
+		fmt.Printf("applied  %v_%v\n", m.Version, m.Identifier)
+	}
+	for _, m := range status.Pending {
+		fmt.Printf("pending  %v_%v\n", m.Version, m.Identifier)
+	}
+}
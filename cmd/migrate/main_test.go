@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	cases := []struct {
+		name    string
+		files   []string
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "Run_MissingCommand",
+			wantErr: "missing command",
+		},
+		{
+			name:    "Run_UnknownCommand",
+			args:    []string{"migrate"},
+			wantErr: "unknown command migrate",
+		},
+		{
+			name:    "Run_UnknownDialect",
+			args:    []string{"-dialect", "oracle", "up"},
+			wantErr: "unknown dialect oracle",
+		},
+		{
+			name:    "Run_UnknownDirtyPolicy",
+			args:    []string{"-dialect", "postgres", "-dirty", "ignore", "up"},
+			wantErr: "unknown dirty policy ignore",
+		},
+		{
+			name:  "Run_Validate",
+			files: []string{"000001_a.up.sql", "000001_a.down.sql", "000002_b.up.sql"},
+			args:  []string{"validate"},
+		},
+		{
+			name:    "Run_Validate_Invalid",
+			files:   []string{"000001_a.up.sql", "000003_b.up.sql", "b.down.sql"},
+			args:    []string{"validate"},
+			wantErr: "versions 2 to 2 are missing",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			dir := t.TempDir()
+			for _, name := range tt.files {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o600))
+			}
+
+			//When
+			err := run(context.Background(), append([]string{"-source", "file://" + dir}, tt.args...))
+
+			//Then
+			if tt.wantErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestRun_Create(t *testing.T) {
+	//Given
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "000001_a.up.sql"), nil, 0o600))
+
+	//When
+	err := run(context.Background(), []string{"-source", "file://" + dir, "-seq", "create", "add", "users"})
+
+	//Then
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "000002_add_users.up.sql"))
+	assert.FileExists(t, filepath.Join(dir, "000002_add_users.down.sql"))
+	assert.NoError(t, run(context.Background(), []string{"-source", "file://" + dir, "validate"}))
+}
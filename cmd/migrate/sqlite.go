@@ -0,0 +1,10 @@
+//go:build cgo
+
+package main
+
+import "github.com/mauricetjmurphy/ms-common/db/dialect/sqlite"
+
+// the SQLite driver requires cgo, the CGO_ENABLED=0 builds support the other dialects.
+func init() {
+	dialects["sqlite"] = sqlite.Dialect
+}
@@ -0,0 +1,79 @@
+//go:build cgo
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mauricetjmurphy/ms-common/db"
+	"github.com/mauricetjmurphy/ms-common/db/dialect/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_SQLite(t *testing.T) {
+	cases := []struct {
+		name        string
+		commands    [][]string
+		wantVersion uint
+	}{
+		{
+			name:        "Run_Up",
+			commands:    [][]string{{"up"}},
+			wantVersion: 2,
+		},
+		{
+			name:        "Run_Up_DryRun",
+			commands:    [][]string{{"-dry-run", "up"}},
+			wantVersion: 0,
+		},
+		{
+			name:        "Run_Down",
+			commands:    [][]string{{"up"}, {"down", "1"}},
+			wantVersion: 1,
+		},
+		{
+			name:        "Run_Goto",
+			commands:    [][]string{{"goto", "1"}},
+			wantVersion: 1,
+		},
+		{
+			name:        "Run_Force",
+			commands:    [][]string{{"force", "2"}, {"status"}},
+			wantVersion: 2,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			dir := t.TempDir()
+			files := map[string]string{
+				"1_create_dummy.up.sql":    "CREATE TABLE dummy (Id INTEGER PRIMARY KEY);",
+				"1_create_dummy.down.sql":  "DROP TABLE dummy;",
+				"2_create_one_to.up.sql":   "CREATE TABLE one_to (Id INTEGER PRIMARY KEY);",
+				"2_create_one_to.down.sql": "DROP TABLE one_to;",
+			}
+			for name, content := range files {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+			}
+			dbFile := filepath.Join(dir, "test.db")
+			flags := []string{"-dialect", "sqlite", "-name", dbFile, "-source", "file://" + dir}
+
+			//When
+			for _, command := range tt.commands {
+				require.NoError(t, run(context.Background(), append(flags, command...)))
+			}
+
+			//Then
+			mi, err := db.NewMigrator(context.Background(), db.Dialect(sqlite.Dialect()), db.Name(dbFile), db.MigrationSourceURL("file://"+dir))
+			require.NoError(t, err)
+			status, err := mi.Status()
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantVersion, status.Version)
+			assert.False(t, status.Dirty)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+
+	"github.com/mauricetjmurphy/ms-common/grpc/auth_context"
+	"github.com/mauricetjmurphy/ms-common/http/middleware/auth"
+	"gorm.io/gorm"
+)
+
+const (
+	auditCreatedBy     = "CreatedBy"
+	auditLastUpdatedBy = "LastUpdatedBy"
+)
+
+type (
+	auditUserKey    struct{}
+	withoutAuditKey struct{}
+)
+
+// WithAuditUser overrides the user stamped on the audit columns of the calls made with the returned context.
+func WithAuditUser(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, auditUserKey{}, userID)
+}
+
+// WithoutAudit opts the calls made with the returned context out of the audit columns stamping.
+func WithoutAudit(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutAuditKey{}, true)
+}
+
+// AuditUser returns the user stamped on the audit columns: the WithAuditUser override, or the
+// authenticated user ID, or the SSO of the gRPC or HTTP request.
+func AuditUser(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	if skip, _ := ctx.Value(withoutAuditKey{}).(bool); skip {
+		return 0, false
+	}
+	if userID, ok := ctx.Value(auditUserKey{}).(uint); ok {
+		return userID, true
+	}
+	if userID := auth_context.UserId(ctx); userID > 0 {
+		return userID, true
+	}
+	for _, sso := range []string{auth_context.SsoID(ctx), auth.GetSsoID(ctx)} {
+		if userID, err := strconv.ParseUint(sso, 10, 0); err == nil && userID > 0 {
+			return uint(userID), true
+		}
+	}
+	return 0, false
+}
+
+// useAudit registers the callbacks stamping the entity.Audit columns with the AuditUser of the
+// statement context. CreatedBy is stamped on create when unset, LastUpdatedBy on create and update.
+func useAudit(tx *gorm.DB) error {
+	if err := tx.Callback().Create().Before("gorm:create").Register("db:audit_create", stampAudit(true)); err != nil {
+		return err
+	}
+	return tx.Callback().Update().Before("gorm:update").Register("db:audit_update", stampAudit(false))
+}
+
+func stampAudit(create bool) func(tx *gorm.DB) {
+	return func(tx *gorm.DB) {
+		stmt := tx.Statement
+		if tx.Error != nil || stmt.Schema == nil || stmt.SkipHooks {
+			return
+		}
+		userID, ok := AuditUser(stmt.Context)
+		if !ok {
+			return
+		}
+		lastUpdatedBy := stmt.Schema.LookUpField(auditLastUpdatedBy)
+		if lastUpdatedBy == nil {
+			return
+		}
+		if !create {
+			stmt.SetColumn(auditLastUpdatedBy, userID, true)
+			return
+		}
+
+		createdBy := stmt.Schema.LookUpField(auditCreatedBy)
+		stamp := func(rv reflect.Value) {
+			if createdBy != nil {
+				if _, zero := createdBy.ValueOf(stmt.Context, rv); zero {
+					tx.AddError(createdBy.Set(stmt.Context, rv, userID))
+				}
+			}
+			tx.AddError(lastUpdatedBy.Set(stmt.Context, rv, userID))
+		}
+		switch stmt.ReflectValue.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < stmt.ReflectValue.Len(); i++ {
+				stamp(reflect.Indirect(stmt.ReflectValue.Index(i)))
+			}
+		case reflect.Struct:
+			stamp(stmt.ReflectValue)
+		}
+	}
+}
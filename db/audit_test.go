@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mauricetjmurphy/ms-common/db/entity"
+	"github.com/mauricetjmurphy/ms-common/grpc/auth_context"
+	"github.com/mauricetjmurphy/ms-common/http/middleware/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type auditDummy struct {
+	*entity.Base
+	Name string `gorm:"column:Name"`
+	*entity.Audit
+}
+
+func (auditDummy) TableName() string { return "dummy" }
+
+func TestAuditUser(t *testing.T) {
+	cases := []struct {
+		name    string
+		ctx     context.Context
+		wantID  uint
+		wantHas bool
+	}{
+		{
+			name: "AuditUser_None",
+			ctx:  context.Background(),
+		},
+		{
+			name:    "AuditUser_UserID",
+			ctx:     auth_context.WithSso(auth_context.WithUserId(context.Background(), 7), "206000001"),
+			wantID:  7,
+			wantHas: true,
+		},
+		{
+			name:    "AuditUser_GRPCSso",
+			ctx:     auth_context.WithSso(context.Background(), "206000001"),
+			wantID:  206000001,
+			wantHas: true,
+		},
+		{
+			name:    "AuditUser_HTTPSso",
+			ctx:     auth.WithSsoID(context.Background(), "206000002"),
+			wantID:  206000002,
+			wantHas: true,
+		},
+		{
+			name:    "AuditUser_Override",
+			ctx:     WithAuditUser(auth_context.WithUserId(context.Background(), 7), 9),
+			wantID:  9,
+			wantHas: true,
+		},
+		{
+			name: "AuditUser_OptOut",
+			ctx:  WithoutAudit(auth_context.WithUserId(context.Background(), 7)),
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//When
+			userID, ok := AuditUser(tt.ctx)
+
+			//Then
+			assert.Equal(t, tt.wantID, userID)
+			assert.Equal(t, tt.wantHas, ok)
+		})
+	}
+}
+
+func TestDB_Audit(t *testing.T) {
+	cases := []struct {
+		name    string
+		fnMocks func(sqlMock sqlmock.Sqlmock)
+		run     func(db DB) error
+	}{
+		{
+			name: "Save_Create_StampsCreatedAndUpdatedBy",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `dummy` (`Name`,`CreatedBy`,`CreatedDatim`,`LastUpdatedBy`,`LastUpdatedDatim`) VALUES (?,?,?,?,?)")).
+					WithArgs("Test", uint(7), sqlmock.AnyArg(), uint(7), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				sqlMock.ExpectCommit()
+			},
+			run: func(db DB) error {
+				ctx := auth_context.WithUserId(context.Background(), 7)
+				return db.Save(ctx, &auditDummy{Name: "Test"})
+			},
+		},
+		{
+			name: "Update_StampsLastUpdatedBy_KeepsCreatedBy",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta("UPDATE `dummy` SET `Name`=?,`CreatedBy`=?,`LastUpdatedBy`=?,`LastUpdatedDatim`=? WHERE `Id` = ?")).
+					WithArgs("Test", uint(3), uint(9), sqlmock.AnyArg(), 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit()
+			},
+			run: func(db DB) error {
+				ctx := WithAuditUser(auth_context.WithUserId(context.Background(), 7), 9)
+				return db.Update(ctx, &auditDummy{Base: entity.NewBase(1), Name: "Test", Audit: &entity.Audit{CreatedBy: 3}})
+			},
+		},
+		{
+			name: "Save_Create_OptOut",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `dummy`")).
+					WithArgs("Test", uint(0), sqlmock.AnyArg(), uint(0), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				sqlMock.ExpectCommit()
+			},
+			run: func(db DB) error {
+				ctx := WithoutAudit(auth_context.WithUserId(context.Background(), 7))
+				return db.Save(ctx, &auditDummy{Name: "Test", Audit: &entity.Audit{}})
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			dialector, sqlMock := newMockDialector(t)
+			tx, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Discard})
+			require.NoError(t, err)
+			require.NoError(t, useAudit(tx))
+			tt.fnMocks(sqlMock)
+
+			//When
+			err = tt.run(&dbImpl{DB: tx})
+
+			//Then
+			assert.NoError(t, err)
+			if err := sqlMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/mauricetjmurphy/ms-common/db/query/criteria"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultBatchSize is the default number of rows written per statement by CreateInBatches and Upsert.
+const DefaultBatchSize = 1000
+
+// UpsertResult counts the rows written by Upsert.
+type UpsertResult struct {
+	// Inserted is the number of new rows.
+	Inserted int64
+	// Updated is the number of existing rows, updated or left unchanged when there is no update column.
+	Updated int64
+}
+
+func (db *dbImpl) CreateInBatches(ctx context.Context, values interface{}) (int64, error) {
+	result := db.withContext(ctx).CreateInBatches(values, db.batch())
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func (db *dbImpl) Upsert(ctx context.Context, values interface{}, conflictColumns []string, updateColumns []string) (*UpsertResult, error) {
+	if len(conflictColumns) == 0 {
+		return nil, errors.New("db : upsert requires the conflict columns")
+	}
+	rows := reflect.Indirect(reflect.ValueOf(values))
+	if rows.Kind() != reflect.Slice && rows.Kind() != reflect.Array {
+		return nil, errors.Errorf("db : upsert requires a slice of values, got %T", values)
+	}
+
+	onConflict := clause.OnConflict{DoNothing: len(updateColumns) == 0}
+	for _, column := range conflictColumns {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: column})
+	}
+	if len(updateColumns) > 0 {
+		onConflict.DoUpdates = clause.AssignmentColumns(updateColumns)
+	}
+
+	res := &UpsertResult{}
+	err := db.withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(values); err != nil {
+			return errors.Wrap(err, "db : failed to parse upsert values")
+		}
+		size := db.batch()
+		for i := 0; i < rows.Len(); i += size {
+			j := i + size
+			if j > rows.Len() {
+				j = rows.Len()
+			}
+			batch := rows.Slice(i, j)
+			inserted, err := upsertBatch(tx, stmt, batch, onConflict, conflictColumns)
+			if err != nil {
+				return err
+			}
+			res.Inserted += inserted
+			res.Updated += int64(batch.Len()) - inserted
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// upsertBatch writes the batch and returns the number of inserted rows. The affected rows reported
+// by the databases only tell the inserts from the updates when the conflicting rows are skipped,
+// MySQL counting an unchanged row zero times as long as the clientFoundRows DSN parameter is off.
+// Otherwise PostgreSQL tells the inserted rows by their xmax system column, while MySQL and SQLite
+// count the conflicting rows before the write: MySQL locks the matched keys and their gaps for the
+// transaction, and SQLite serializes the writers.
+func upsertBatch(tx *gorm.DB, stmt *gorm.Statement, batch reflect.Value, onConflict clause.OnConflict, conflictColumns []string) (int64, error) {
+	if onConflict.DoNothing {
+		result := tx.Clauses(onConflict).Create(batch.Interface())
+		return result.RowsAffected, result.Error
+	}
+	if tx.Dialector.Name() == "postgres" {
+		return upsertReturning(tx, stmt, batch, onConflict)
+	}
+
+	conds, err := conflictConds(stmt, batch, conflictColumns)
+	if err != nil {
+		return 0, err
+	}
+	existing := tx.Session(&gorm.Session{NewDB: true}).Table(stmt.Schema.Table).Where(conds)
+	if tx.Dialector.Name() == "mysql" {
+		existing = existing.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var count int64
+	if err := existing.Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Clauses(onConflict).Create(batch.Interface()).Error; err != nil {
+		return 0, err
+	}
+	return int64(batch.Len()) - count, nil
+}
+
+// upsertReturning writes the batch on PostgreSQL, returning whether each row was inserted, its xmax
+// being zero, along with the columns gorm sets back on the values.
+func upsertReturning(tx *gorm.DB, stmt *gorm.Statement, batch reflect.Value, onConflict clause.OnConflict) (int64, error) {
+	fields := stmt.Schema.FieldsWithDefaultDBValue
+	returning := clause.Returning{}
+	for _, field := range fields {
+		returning.Columns = append(returning.Columns, clause.Column{Name: field.DBName})
+	}
+	returning.Columns = append(returning.Columns, clause.Column{Name: "(xmax = 0)", Raw: true})
+
+	dry := tx.Session(&gorm.Session{DryRun: true}).Clauses(onConflict, returning).Create(batch.Interface())
+	if dry.Error != nil {
+		return 0, dry.Error
+	}
+	rows, err := tx.Statement.ConnPool.QueryContext(tx.Statement.Context, dry.Statement.SQL.String(), dry.Statement.Vars...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var inserted int64
+	for i := 0; rows.Next(); i++ {
+		dest := make([]interface{}, 0, len(returning.Columns))
+		for _, field := range fields {
+			dest = append(dest, reflect.New(field.FieldType).Interface())
+		}
+		var isNew bool
+		dest = append(dest, &isNew)
+		if err := rows.Scan(dest...); err != nil {
+			return 0, err
+		}
+		// every row is returned in the order of the values, as gorm assumes setting back the primary keys.
+		if i < batch.Len() {
+			row := reflect.Indirect(batch.Index(i))
+			for k, field := range fields {
+				if err := field.Set(tx.Statement.Context, row, reflect.ValueOf(dest[k]).Elem().Interface()); err != nil {
+					return 0, err
+				}
+			}
+		}
+		if isNew {
+			inserted++
+		}
+	}
+	return inserted, rows.Err()
+}
+
+// conflictConds matches the stored rows conflicting with the batch rows on the conflict columns.
+func conflictConds(stmt *gorm.Statement, batch reflect.Value, conflictColumns []string) (criteria.Expr, error) {
+	var ors []criteria.Expr
+	for i := 0; i < batch.Len(); i++ {
+		row := reflect.Indirect(batch.Index(i))
+		var ands []criteria.Expr
+		for _, column := range conflictColumns {
+			field := stmt.Schema.LookUpField(column)
+			if field == nil {
+				return nil, errors.Errorf("db : unknown conflict column %v of %v", column, stmt.Schema.Name)
+			}
+			value, _ := field.ValueOf(stmt.Context, row)
+			ands = append(ands, criteria.Eq(field.DBName, value))
+		}
+		ors = append(ors, criteria.And(ands...))
+	}
+	return criteria.Or(ors...), nil
+}
+
+// batch returns the configured batch size.
+func (db *dbImpl) batch() int {
+	if db.batchSize > 0 {
+		return db.batchSize
+	}
+	return DefaultBatchSize
+}
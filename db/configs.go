@@ -1,8 +1,13 @@
 package db
 
 import (
-	"fmt"
+	"io/fs"
 	"strings"
+	"time"
+
+	"github.com/mauricetjmurphy/ms-common/db/dialect"
+	"github.com/mauricetjmurphy/ms-common/db/migrate"
+	"gorm.io/gorm/logger"
 )
 
 const (
@@ -12,6 +17,8 @@ const (
 
 // Config includes required and optional parameters for OpenConnection.
 type Config struct {
+	// Dialect is the SQL database to connect to, defaults to MySQL.
+	Dialect            dialect.Dialect
 	Host               string
 	Port               int
 	Name               string
@@ -20,17 +27,71 @@ type Config struct {
 	AWSRegion          string
 	AWSSecretID        string
 	MigrationSourceURL string
+	// MigrationFS is the file system holding the migrations, e.g. an embed.FS compiled into the binary,
+	// MigrationSourceURL is then the directory of the migrations in MigrationFS.
+	MigrationFS fs.FS
+	// MigrationDirtyPolicy is the recovery of a dirty schema version on migration, defaults to migrate.DirtyFail.
+	MigrationDirtyPolicy migrate.DirtyPolicy
+	// MigrationDryRun logs the planned migrations without applying them.
+	MigrationDryRun bool
+	// ReaderHosts are the read replica endpoints sharing the primary credentials and schema.
+	ReaderHosts []string
+	// ReaderAWSSecretID is the AWS secret ID holding the read replica connection, e.g. an Aurora reader endpoint.
+	ReaderAWSSecretID string
+
+	// MaxOpenConns, MaxIdleConns, ConnMaxLifetime and ConnMaxIdleTime tune the connection pools,
+	// zero values keep the database/sql defaults.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// Loc is the location used to parse and format the time.Time values, defaults to UTC.
+	Loc *time.Location
+	// TLS is the TLS mode of the connections: true, false, skip-verify, preferred or a registered config name.
+	TLS          string
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// Params are the extra DSN parameters, e.g. the session system variables.
+	Params map[string]string
+
+	// BatchSize is the number of rows written per statement by CreateInBatches and Upsert, defaults to DefaultBatchSize.
+	BatchSize int
+
+	// LogLevel is the statements logging level, defaults to logger.Info logging every statement at debug level.
+	LogLevel logger.LogLevel
+	// SlowThreshold is the duration above which a statement is logged as slow at warn level, defaults
+	// to DefaultSlowThreshold, zero disables it.
+	SlowThreshold time.Duration
+	// LogParams logs the bound parameters of the statements, redacted by default.
+	LogParams bool
+	// Metrics receives the metrics of every executed statement, none by default.
+	Metrics MetricsSink
+
+	// WaitTimeout is how long New waits, with backoff, for the database to be reachable before failing,
+	// zero fails on the first connection error.
+	WaitTimeout time.Duration
+
+	// SecretRefreshInterval is the interval to check the AWS secret for a rotated version, zero disables it.
+	SecretRefreshInterval time.Duration
 }
 
 // NewConfigs creates Config on given configuration options.
 func NewConfigs(opts ...Option) *Config {
 	cf := &Config{
-		Host: DefaultHost,
-		Port: DefaultPort,
+		Dialect:               dialect.MySQL(),
+		Host:                  DefaultHost,
+		LogLevel:              logger.Info,
+		SlowThreshold:         DefaultSlowThreshold,
+		SecretRefreshInterval: DefaultSecretRefreshInterval,
 	}
 	for _, opt := range opts {
 		opt(cf)
 	}
+	if cf.Port == 0 {
+		cf.Port = cf.Dialect.DefaultPort()
+	}
 	return cf
 }
 
@@ -39,27 +100,62 @@ func (c *Config) IsLocal() bool {
 }
 
 func (c *Config) RequiredMigration() bool {
-	return c != nil && len(strings.TrimSpace(c.MigrationSourceURL)) > 0
+	return c != nil && (len(strings.TrimSpace(c.MigrationSourceURL)) > 0 || c.MigrationFS != nil)
+}
+
+func (c *Config) HasReplicas() bool {
+	return c != nil && (len(c.ReaderHosts) > 0 || c.ReaderAWSSecretID != "")
 }
 
 func (c *Config) toDSN() *dsnConf {
-	return &dsnConf{
+	return c.withSession(&dsnConf{
 		Host:     c.Host,
 		Port:     c.Port,
 		Name:     c.Name,
 		User:     c.User,
 		Password: c.Password,
-	}
+	})
+}
+
+// withSession applies the session parameters on given connection.
+func (c *Config) withSession(dsn *dsnConf) *dsnConf {
+	dsn.Loc = c.Loc
+	dsn.TLS = c.TLS
+	dsn.DialTimeout = c.DialTimeout
+	dsn.ReadTimeout = c.ReadTimeout
+	dsn.WriteTimeout = c.WriteTimeout
+	dsn.Params = c.Params
+	return dsn
+}
+
+// poolConfigurer is implemented by the sql.DB connection pools.
+type poolConfigurer interface {
+	SetMaxOpenConns(n int)
+	SetMaxIdleConns(n int)
+	SetConnMaxLifetime(d time.Duration)
+	SetConnMaxIdleTime(d time.Duration)
 }
 
-type dsnConf struct {
-	Host     string
-	Port     int
-	Name     string
-	User     string
-	Password string
+// configurePool applies the pool settings on given connection pool.
+func (c *Config) configurePool(pool poolConfigurer) {
+	if c.MaxOpenConns > 0 {
+		pool.SetMaxOpenConns(c.MaxOpenConns)
+	}
+	if c.MaxIdleConns > 0 {
+		pool.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	if c.ConnMaxLifetime > 0 {
+		pool.SetConnMaxLifetime(c.ConnMaxLifetime)
+	}
+	if c.ConnMaxIdleTime > 0 {
+		pool.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+	}
 }
 
-func (dsn *dsnConf) toDSNConnString() string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?multiStatements=true&parseTime=true", dsn.User, dsn.Password, dsn.Host, dsn.Port, dsn.Name)
+// dsnConf includes the connection parameters of one database endpoint.
+type dsnConf = dialect.Conn
+
+// dsn builds the connection string of the configured dialect.
+func (c *Config) dsn(conn *dsnConf) string {
+	return c.Dialect.DSN(conn)
 }
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_toDSNConnString(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{
+			name: "Default_Session",
+			opts: []Option{User("user"), Password("secret"), Name("settings")},
+			want: "user:secret@tcp(127.0.0.1:3306)/settings?multiStatements=true&parseTime=true",
+		},
+		{
+			name: "Tuned_Session",
+			opts: []Option{
+				User("user"),
+				Password("secret"),
+				Host("db.local"),
+				Port(3307),
+				Name("settings"),
+				Location(time.Local),
+				TLS("skip-verify"),
+				DialTimeout(5 * time.Second),
+				ReadTimeout(30 * time.Second),
+				WriteTimeout(10 * time.Second),
+				Param("time_zone", "'+00:00'"),
+			},
+			want: "user:secret@tcp(db.local:3307)/settings?loc=Local&multiStatements=true&parseTime=true&readTimeout=30s&timeout=5s&tls=skip-verify&writeTimeout=10s&time_zone=%27%2B00%3A00%27",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfigs(tt.opts...)
+			assert.Equal(t, tt.want, cfg.dsn(cfg.toDSN()))
+		})
+	}
+}
+
+func TestConfig_configurePool(t *testing.T) {
+	//Given
+	sqlDB, _, err := sqlmock.New()
+	require.NoError(t, err)
+	cfg := NewConfigs(MaxOpenConns(20), MaxIdleConns(5), ConnMaxLifetime(time.Minute), ConnMaxIdleTime(time.Second))
+
+	//When
+	cfg.configurePool(sqlDB)
+
+	//Then
+	assert.Equal(t, 20, sqlDB.Stats().MaxOpenConnections)
+}
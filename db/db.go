@@ -2,26 +2,45 @@ package db
 
 import (
 	"context"
-	"time"
+	"database/sql"
+	"io"
+	"reflect"
 
-	"github.com/mauricetjmurphy/ms-common/clients/aws/secrets"
+	"github.com/mauricetjmurphy/ms-common/db/entity"
 	"github.com/mauricetjmurphy/ms-common/db/migrate"
 	"github.com/mauricetjmurphy/ms-common/db/query"
-	"github.com/mauricetjmurphy/ms-common/logx"
 	"github.com/pkg/errors"
-	"github.com/sirupsen/logrus"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
-	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
 )
 
 //go:generate mockery --output dbmocks --outpkg dbmocks --name DB
 type DB interface {
 	Save(ctx context.Context, value interface{}) error
 	Update(ctx context.Context, value interface{}) error
+	// Delete soft deletes the entities of a model having the DeletedDatim column, e.g. embedding
+	// entity.SoftDelete, and hard deletes the others.
 	Delete(ctx context.Context, value interface{}) error
+	// HardDelete deletes the entity and its associations, soft deletable or not.
+	HardDelete(ctx context.Context, value interface{}) error
+	// Restore clears the soft delete columns of the entity.
+	Restore(ctx context.Context, value interface{}) error
 	Query() *query.Query
+	// CreateInBatches inserts the slice of values in batches of the configured batch size,
+	// returning the number of inserted rows.
+	CreateInBatches(ctx context.Context, values interface{}) (int64, error)
+	// Upsert inserts the slice of values in batches, updating the updateColumns of the rows
+	// conflicting on the conflictColumns, or skipping them when there is no update column.
+	// On MySQL, the counts are only exact with the clientFoundRows DSN parameter off.
+	Upsert(ctx context.Context, values interface{}, conflictColumns []string, updateColumns []string) (*UpsertResult, error)
+	// Stats returns the statistics of the primary connection pool.
+	Stats() sql.DBStats
+	// Ping checks the database is reachable and reports its health.
+	Ping(ctx context.Context) (*Health, error)
+	// Transaction runs fn as one unit of work on a transaction scoped DB.
+	// Calling Transaction on the given tx creates a nested savepoint.
+	Transaction(ctx context.Context, fn func(tx DB) error) error
 	DBInstance() *gorm.DB
 	Close() error
 }
@@ -29,150 +48,309 @@ type DB interface {
 // DB is a wrapper for gorm.DB.
 type dbImpl struct {
 	*gorm.DB
+	// resolver routes the reads to the replicas when configured.
+	resolver *dbresolver.DBResolver
+	// credentials are the secret backed credentials watched for rotation.
+	credentials []*credentials
+	// batchSize is the number of rows written per statement by CreateInBatches and Upsert.
+	batchSize int
 }
 
 // New creates new DB instance on given context and configuration options.
 func New(ctx context.Context, opts ...Option) (DB, error) {
 	dbConfig := NewConfigs(opts...)
 
-	dsnDBConfig, err := loadDSNConfig(ctx, dbConfig)
-	if err != nil {
-		return nil, err
+	var (
+		creds       *credentials
+		dsnDBConfig = dbConfig.toDSN()
+		err         error
+	)
+	if !dbConfig.IsLocal() {
+		if creds, err = newSecretCredentials(ctx, dbConfig, dbConfig.AWSSecretID); err != nil {
+			return nil, err
+		}
+		dsnDBConfig = creds.get()
+	}
+
+	if dbConfig.WaitTimeout > 0 {
+		if err := waitForDB(ctx, dbConfig, dsnDBConfig); err != nil {
+			return nil, err
+		}
 	}
 
 	if dbConfig.RequiredMigration() {
-		if err := runMigration(dsnDBConfig, dbConfig.MigrationSourceURL); err != nil {
+		if err := runMigration(dbConfig, dsnDBConfig); err != nil {
 			return nil, err
 		}
 	}
 
-	tx, err := gorm.Open(mysql.Open(dsnDBConfig.toDSNConnString()), &gorm.Config{
-		Logger: &dbLogger{},
+	primary := dbConfig.Dialect.Dialector(dbConfig.dsn(dsnDBConfig), nil)
+	if creds != nil {
+		primary = creds.dialector("")
+	}
+	tx, err := gorm.Open(primary, &gorm.Config{
+		Logger: newLogger(dbConfig),
 	})
 
 	if err != nil {
 		return nil, errors.Wrap(err, "db : failed initialize db session")
 	}
 
-	_, err = tx.DB()
+	if err := useAudit(tx); err != nil {
+		return nil, errors.Wrap(err, "db : failed to register audit callbacks")
+	}
+
+	if dbConfig.Metrics != nil {
+		if err := useMetrics(tx, dbConfig.Metrics); err != nil {
+			return nil, errors.Wrap(err, "db : failed to register metrics callbacks")
+		}
+	}
+
+	sqlDB, err := tx.DB()
 	if err != nil {
 		return nil, errors.Wrap(err, "db : failed open connection")
 	}
+	dbConfig.configurePool(sqlDB)
+
+	db := &dbImpl{DB: tx, batchSize: dbConfig.BatchSize}
+	if creds != nil {
+		db.credentials = append(db.credentials, creds)
+	}
+
+	if dbConfig.HasReplicas() {
+		replicas, readerCreds, err := openReplicas(ctx, dbConfig, dsnDBConfig, creds)
+		if err != nil {
+			return nil, err
+		}
+		if readerCreds != nil {
+			db.credentials = append(db.credentials, readerCreds)
+		}
+		if db.resolver, err = useReplicas(tx, replicas); err != nil {
+			return nil, errors.Wrap(err, "db : failed to open read replica connections")
+		}
+		_ = db.resolver.Call(func(connPool gorm.ConnPool) error {
+			if pool, ok := connPool.(poolConfigurer); ok {
+				dbConfig.configurePool(pool)
+			}
+			return nil
+		})
+	}
 
-	return &dbImpl{tx}, nil
+	for _, c := range db.credentials {
+		c.watch(dbConfig.SecretRefreshInterval)
+	}
+
+	return db, nil
 }
 
 // Wrap creates one DB instance from existing connection.
 func Wrap(tx *gorm.DB) DB {
-	return &dbImpl{tx}
+	return &dbImpl{DB: tx}
 }
 
 func (db *dbImpl) Save(ctx context.Context, value interface{}) error {
-	if ctx != nil {
-		db.WithContext(ctx)
-	}
-	results := db.Session(&gorm.Session{FullSaveAssociations: true}).Save(value)
+	results := db.withContext(ctx).Session(&gorm.Session{FullSaveAssociations: true}).Save(value)
 	if results.Error != nil {
 		return results.Error
 	}
 	return nil
 }
 
+// Update updates all the columns of the entity. An entity embedding entity.Version is only
+// updated when its version is the stored one, the version is then incremented, otherwise
+// a *ConflictError is returned.
 func (db *dbImpl) Update(ctx context.Context, value interface{}) error {
-	if ctx != nil {
-		db.WithContext(ctx)
+	if versioned, ok := value.(entity.Versioned); ok && versioned.LockVersion() != nil {
+		return db.updateVersion(ctx, value, versioned.LockVersion())
 	}
-	if result := db.Session(&gorm.Session{FullSaveAssociations: true}).Select("*").Updates(value); result.Error != nil {
+	if result := db.withContext(ctx).Session(&gorm.Session{FullSaveAssociations: true}).Select("*").Updates(value); result.Error != nil {
 		return result.Error
 	}
 	return nil
 }
 
-func (db *dbImpl) Delete(ctx context.Context, value interface{}) error {
-	if ctx != nil {
-		db.WithContext(ctx)
+func (db *dbImpl) updateVersion(ctx context.Context, value interface{}, version *entity.Version) error {
+	// the version condition would otherwise pass the missing where clause check of gorm,
+	// updating every row at that version.
+	stmt := &gorm.Statement{DB: db.withContext(ctx)}
+	if err := stmt.Parse(value); err != nil {
+		return errors.Wrap(err, "db : failed to parse model")
 	}
-	if result := db.Unscoped().Select(clause.Associations).Delete(value); result.Error != nil {
-		return result.Error
+	reflectValue := reflect.Indirect(reflect.ValueOf(value))
+	if len(stmt.Schema.PrimaryFields) == 0 {
+		return gorm.ErrMissingWhereClause
+	}
+	for _, field := range stmt.Schema.PrimaryFields {
+		if _, isZero := field.ValueOf(ctx, reflectValue); isZero {
+			return gorm.ErrMissingWhereClause
+		}
+	}
+
+	current := version.Version
+	version.Version++
+	// the update runs in a transaction rolled back on conflict, not to save the associations
+	// of an entity left unchanged.
+	err := db.withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Session(&gorm.Session{FullSaveAssociations: true}).
+			Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: versionColumn}, Value: current}).
+			Select("*").Updates(value)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return &ConflictError{Table: result.Statement.Table, Version: current}
+		}
+		return nil
+	})
+	if err != nil {
+		version.Version = current
+		return err
 	}
 	return nil
 }
 
-func (db *dbImpl) Query() *query.Query {
-	return query.New(db.DB)
+func (db *dbImpl) Delete(ctx context.Context, value interface{}) error {
+	tx := db.withContext(ctx)
+	columns, err := softDeleteColumns(tx, value)
+	if err != nil {
+		return err
+	}
+	if columns == nil {
+		return db.HardDelete(ctx, value)
+	}
+	// the columns are updated over the whole value: a slice, a value or an entity whose embedded
+	// soft delete is nil are soft deleted as well, gorm setting the updated fields back when addressable.
+	columns[entity.DeletedDatimColumn] = gorm.DeletedAt{Time: tx.NowFunc(), Valid: true}
+	if userID, ok := AuditUser(ctx); ok {
+		if _, ok := columns[entity.DeletedByColumn]; ok {
+			columns[entity.DeletedByColumn] = &userID
+		}
+	}
+	if result := tx.Model(value).Updates(columns); result.Error != nil {
+		return result.Error
+	}
+	return nil
 }
 
-func (db *dbImpl) DBInstance() *gorm.DB {
-	return db.DB
+func (db *dbImpl) HardDelete(ctx context.Context, value interface{}) error {
+	if result := db.withContext(ctx).Unscoped().Select(clause.Associations).Delete(value); result.Error != nil {
+		return result.Error
+	}
+	return nil
 }
 
-func (db *dbImpl) Close() error {
-	sqlDB, err := db.DB.DB()
+func (db *dbImpl) Restore(ctx context.Context, value interface{}) error {
+	tx := db.withContext(ctx)
+	columns, err := softDeleteColumns(tx, value)
 	if err != nil {
-		return errors.Wrap(err, "db : failed to get db")
+		return err
 	}
-	return sqlDB.Close()
+	if columns == nil {
+		return errors.Errorf("db : %T is not soft deletable", value)
+	}
+	if result := tx.Unscoped().Model(value).Updates(columns); result.Error != nil {
+		return result.Error
+	}
+	return nil
 }
 
-type dbLogger struct {
+// softDeleteColumns returns the soft delete columns of the value model set to nil, or nil when the
+// model has no DeletedDatim column.
+func softDeleteColumns(tx *gorm.DB, value interface{}) (map[string]interface{}, error) {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(value); err != nil {
+		return nil, errors.Wrap(err, "db : failed to parse model")
+	}
+	if stmt.Schema.LookUpField(entity.DeletedDatimColumn) == nil {
+		return nil, nil
+	}
+	columns := map[string]interface{}{entity.DeletedDatimColumn: nil}
+	if stmt.Schema.LookUpField(entity.DeletedByColumn) != nil {
+		columns[entity.DeletedByColumn] = nil
+	}
+	return columns, nil
 }
 
-func (l *dbLogger) LogMode(logger.LogLevel) logger.Interface {
-	return l
+// Transaction begins a transaction and runs fn with a DB bound to it. The transaction is
+// committed when fn returns nil, and rolled back when fn returns an error or panics.
+func (db *dbImpl) Transaction(ctx context.Context, fn func(tx DB) error) error {
+	return db.withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&dbImpl{DB: tx, batchSize: db.batchSize})
+	})
 }
 
-func (l *dbLogger) Info(ctx context.Context, s string, args ...interface{}) {
-	logx.WithContext(ctx).Infof(s, args...)
+func (db *dbImpl) Query() *query.Query {
+	return query.New(db.DB)
 }
 
-func (l *dbLogger) Warn(ctx context.Context, s string, args ...interface{}) {
-	logx.WithContext(ctx).Warnf(s, args...)
+func (db *dbImpl) DBInstance() *gorm.DB {
+	return db.DB
 }
 
-func (l *dbLogger) Error(ctx context.Context, s string, args ...interface{}) {
-	logx.WithContext(ctx).Errorf(s, args...)
+func (db *dbImpl) Stats() sql.DBStats {
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return sql.DBStats{}
+	}
+	return sqlDB.Stats()
 }
 
-func (l *dbLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
-	elapsed := time.Since(begin)
-	sql, _ := fc()
-	fields := logx.Fields{}
-	logx.WithContext(ctx).WithFields(logrus.Fields(fields)).Debugf("%s [%s]", sql, elapsed)
+// withContext returns a session bound to ctx, so that cancellation, deadlines and
+// tracing segments carried by ctx reach the underlying SQL calls.
+func (db *dbImpl) withContext(ctx context.Context) *gorm.DB {
+	if ctx == nil {
+		return db.DB
+	}
+	return db.DB.WithContext(ctx)
 }
 
-func loadDSNConfig(ctx context.Context, cfg *Config) (*dsnConf, error) {
-	if cfg.IsLocal() {
-		return cfg.toDSN(), nil
+func (db *dbImpl) Close() error {
+	for _, c := range db.credentials {
+		c.Close()
 	}
-
-	sm, err := secrets.New(ctx, cfg.AWSRegion)
+	if db.resolver != nil {
+		// the resolver holds the primary and all the replica connections.
+		return db.resolver.Call(func(connPool gorm.ConnPool) error {
+			if closer, ok := connPool.(io.Closer); ok {
+				return closer.Close()
+			}
+			return nil
+		})
+	}
+	sqlDB, err := db.DB.DB()
 	if err != nil {
-		return nil, errors.Wrapf(err, "db : failed to create secrets managers instance on region %v", cfg.AWSRegion)
+		return errors.Wrap(err, "db : failed to get db")
 	}
+	return sqlDB.Close()
+}
 
-	secret, err := sm.GetSecret(ctx, cfg.AWSSecretID)
-	if err != nil {
-		return nil, errors.Wrapf(err, "db : failed to load GetSecret(%v) on region %v", cfg.AWSSecretID, cfg.AWSRegion)
+// openReplicas opens the read replica connections from the static reader hosts, which share the
+// primary connection parameters, and from the reader secret.
+func openReplicas(ctx context.Context, cfg *Config, primary *dsnConf, creds *credentials) ([]gorm.Dialector, *credentials, error) {
+	var replicas []gorm.Dialector
+	for _, host := range cfg.ReaderHosts {
+		if creds != nil {
+			replicas = append(replicas, creds.dialector(host))
+		} else {
+			replicas = append(replicas, cfg.Dialect.Dialector(cfg.dsn(primary.WithHost(host)), nil))
+		}
 	}
 
-	return &dsnConf{
-		Host:     secret.Host,
-		Port:     secret.Port,
-		Name:     secret.Name,
-		User:     secret.Username,
-		Password: secret.Password,
-	}, nil
+	var readerCreds *credentials
+	if cfg.ReaderAWSSecretID != "" {
+		var err error
+		if readerCreds, err = newSecretCredentials(ctx, cfg, cfg.ReaderAWSSecretID); err != nil {
+			return nil, nil, err
+		}
+		replicas = append(replicas, readerCreds.dialector(""))
+	}
+	return replicas, readerCreds, nil
 }
 
-func runMigration(dns *dsnConf, sourceURL string) error {
-	mi, err := migrate.New(migrate.Options{
-		DSN:       dns.toDSNConnString(),
-		DBName:    dns.Name,
-		SourceURL: sourceURL,
-	})
-
+func runMigration(cfg *Config, dsn *dsnConf) error {
+	mi, err := newMigrator(cfg, dsn)
 	if err != nil {
-		return errors.Wrap(err, "db : failed to create migrate instance")
+		return err
 	}
 
 	if err = mi.Migrate(); err != nil {
@@ -181,3 +359,34 @@ func runMigration(dns *dsnConf, sourceURL string) error {
 
 	return nil
 }
+
+// NewMigrator creates the migrator of the database on given configuration options. The credentials
+// are resolved as New does, from the options or from the AWS secret.
+func NewMigrator(ctx context.Context, opts ...Option) (migrate.Migrator, error) {
+	cfg := NewConfigs(opts...)
+	dsn := cfg.toDSN()
+	if !cfg.IsLocal() {
+		creds, err := newSecretCredentials(ctx, cfg, cfg.AWSSecretID)
+		if err != nil {
+			return nil, err
+		}
+		dsn = creds.get()
+	}
+	return newMigrator(cfg, dsn)
+}
+
+func newMigrator(cfg *Config, dsn *dsnConf) (migrate.Migrator, error) {
+	mi, err := migrate.New(migrate.Options{
+		DSN:         cfg.dsn(dsn),
+		DBName:      dsn.Name,
+		SourceURL:   cfg.MigrationSourceURL,
+		SourceFS:    cfg.MigrationFS,
+		Dialect:     cfg.Dialect,
+		DirtyPolicy: cfg.MigrationDirtyPolicy,
+		DryRun:      cfg.MigrationDryRun,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "db : failed to create migrate instance")
+	}
+	return mi, nil
+}
@@ -0,0 +1,503 @@
+package db_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mauricetjmurphy/ms-common/db"
+	"github.com/mauricetjmurphy/ms-common/db/dbmocks"
+	"github.com/mauricetjmurphy/ms-common/db/entity"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type DummyTable struct {
+	*entity.Base
+	Name string `gorm:"column:Name"`
+}
+
+func (DummyTable) TableName() string { return "dummy" }
+
+func TestDB_Transaction(t *testing.T) {
+	errRollback := errors.New("rollback")
+
+	cases := []struct {
+		name    string
+		fnMocks func(sqlMock sqlmock.Sqlmock)
+		fn      func(tx db.DB) error
+		wantErr error
+	}{
+		{
+			name: "Transaction_Commit",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec("INSERT INTO `dummy`").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				sqlMock.ExpectQuery("SELECT \\* FROM `dummy`").
+					WillReturnRows(sqlmock.NewRows([]string{"Id", "Name"}).AddRow(1, "Test"))
+				sqlMock.ExpectCommit()
+			},
+			fn: func(tx db.DB) error {
+				if err := tx.Save(context.Background(), &DummyTable{Name: "Test"}); err != nil {
+					return err
+				}
+				var results []*DummyTable
+				return tx.Query().Find(context.Background(), &results)
+			},
+		},
+		{
+			name: "Transaction_Rollback_OnError",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec("INSERT INTO `dummy`").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				sqlMock.ExpectRollback()
+			},
+			fn: func(tx db.DB) error {
+				if err := tx.Save(context.Background(), &DummyTable{Name: "Test"}); err != nil {
+					return err
+				}
+				return errRollback
+			},
+			wantErr: errRollback,
+		},
+		{
+			name: "Transaction_Nested_Savepoint",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec("SAVEPOINT").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				sqlMock.ExpectExec("INSERT INTO `dummy`").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				sqlMock.ExpectExec("ROLLBACK TO SAVEPOINT").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				sqlMock.ExpectCommit()
+			},
+			fn: func(tx db.DB) error {
+				err := tx.Transaction(context.Background(), func(nested db.DB) error {
+					if err := nested.Save(context.Background(), &DummyTable{Name: "Test"}); err != nil {
+						return err
+					}
+					return errRollback
+				})
+				assert.Equal(t, errRollback, err)
+				return nil
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			dbMock, sqlMock := dbmocks.NewSqlMock()
+			tt.fnMocks(sqlMock)
+
+			//When
+			err := db.Wrap(dbMock).Transaction(context.Background(), tt.fn)
+
+			//Then
+			assert.Equal(t, tt.wantErr, err)
+			if err := sqlMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestDB_Transaction_RollbackOnPanic(t *testing.T) {
+	//Given
+	dbMock, sqlMock := dbmocks.NewSqlMock()
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectRollback()
+
+	//When
+	assert.Panics(t, func() {
+		_ = db.Wrap(dbMock).Transaction(context.Background(), func(tx db.DB) error {
+			panic("unexpected")
+		})
+	})
+
+	//Then
+	if err := sqlMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDB_Transaction_Mock(t *testing.T) {
+	//Given
+	dbMock, txMock := &dbmocks.DB{}, &dbmocks.DB{}
+	dbmocks.MockTransaction(dbMock, txMock)
+	txMock.On("Save", context.Background(), &DummyTable{Name: "Test"}).Return(nil)
+
+	//When
+	err := dbMock.Transaction(context.Background(), func(tx db.DB) error {
+		return tx.Save(context.Background(), &DummyTable{Name: "Test"})
+	})
+
+	//Then
+	assert.NoError(t, err)
+	dbMock.AssertExpectations(t)
+	txMock.AssertExpectations(t)
+}
+
+func TestDB_Save_ContextCanceled(t *testing.T) {
+	//Given
+	dbMock, sqlMock := dbmocks.NewSqlMock()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	//When
+	err := db.Wrap(dbMock).Save(ctx, &DummyTable{Name: "Test"})
+
+	//Then
+	assert.ErrorIs(t, err, context.Canceled)
+	if err := sqlMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+type VersionedTable struct {
+	*entity.Base
+	Name string `gorm:"column:Name"`
+	entity.Version
+}
+
+func (VersionedTable) TableName() string { return "versioned" }
+
+func TestDB_Update_Version(t *testing.T) {
+	cases := []struct {
+		name        string
+		base        *entity.Base
+		fnMocks     func(sqlMock sqlmock.Sqlmock)
+		wantErr     func(err error) bool
+		wantVersion uint
+	}{
+		{
+			name: "Update_VersionMatched_Incremented",
+			base: entity.NewBase(1),
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta("UPDATE `versioned` SET `Name`=?,`Version`=? WHERE `versioned`.`Version` = ? AND `Id` = ?")).
+					WithArgs("Test", 4, 3, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit()
+			},
+			wantVersion: 4,
+		},
+		{
+			name: "Update_VersionStale_ConflictRolledBack",
+			base: entity.NewBase(1),
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta("UPDATE `versioned` SET `Name`=?,`Version`=? WHERE `versioned`.`Version` = ? AND `Id` = ?")).
+					WithArgs("Test", 4, 3, 1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				sqlMock.ExpectRollback()
+			},
+			wantErr: func(err error) bool {
+				return db.IsConflict(err) && status.Code(err) == codes.Aborted
+			},
+			wantVersion: 3,
+		},
+		{
+			name:    "Update_ZeroID_MissingWhereClause",
+			base:    &entity.Base{},
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {},
+			wantErr: func(err error) bool {
+				return errors.Is(err, gorm.ErrMissingWhereClause)
+			},
+			wantVersion: 3,
+		},
+		{
+			name:    "Update_NilBase_MissingWhereClause",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {},
+			wantErr: func(err error) bool {
+				return errors.Is(err, gorm.ErrMissingWhereClause)
+			},
+			wantVersion: 3,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			dbMock, sqlMock := dbmocks.NewSqlMock()
+			tt.fnMocks(sqlMock)
+			value := &VersionedTable{Base: tt.base, Name: "Test", Version: entity.Version{Version: 3}}
+
+			//When
+			err := db.Wrap(dbMock).Update(context.Background(), value)
+
+			//Then
+			if tt.wantErr != nil {
+				assert.True(t, tt.wantErr(err), "unexpected error %v", err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantVersion, value.Version.Version)
+			if err := sqlMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+type SoftDeleteTable struct {
+	*entity.Base
+	Name string `gorm:"column:Name"`
+	entity.SoftDelete
+}
+
+func (SoftDeleteTable) TableName() string { return "soft" }
+
+func TestDB_SoftDelete(t *testing.T) {
+	cases := []struct {
+		name        string
+		fnMocks     func(sqlMock sqlmock.Sqlmock)
+		run         func(d db.DB, value *SoftDeleteTable) error
+		wantDeleted bool
+	}{
+		{
+			name: "Delete_SoftDeletes",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta("UPDATE `soft` SET `DeletedBy`=?,`DeletedDatim`=? WHERE `soft`.`DeletedDatim` IS NULL AND `Id` = ?")).
+					WithArgs(uint(7), sqlmock.AnyArg(), 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit()
+			},
+			run: func(d db.DB, value *SoftDeleteTable) error {
+				return d.Delete(db.WithAuditUser(context.Background(), 7), value)
+			},
+			wantDeleted: true,
+		},
+		{
+			name: "HardDelete_Deletes",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta("DELETE FROM `soft` WHERE `soft`.`Id` = ?")).
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit()
+			},
+			run: func(d db.DB, value *SoftDeleteTable) error {
+				return d.HardDelete(context.Background(), value)
+			},
+		},
+		{
+			name: "Restore_ClearsDeleted",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta("UPDATE `soft` SET `DeletedBy`=?,`DeletedDatim`=? WHERE `Id` = ?")).
+					WithArgs(nil, nil, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit()
+			},
+			run: func(d db.DB, value *SoftDeleteTable) error {
+				return d.Restore(context.Background(), value)
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			dbMock, sqlMock := dbmocks.NewSqlMock()
+			tt.fnMocks(sqlMock)
+			value := &SoftDeleteTable{Base: entity.NewBase(1), Name: "Test"}
+
+			//When
+			err := tt.run(db.Wrap(dbMock), value)
+
+			//Then
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantDeleted, value.IsDeleted())
+			if err := sqlMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+type SoftDeletePtrTable struct {
+	*entity.Base
+	Name string `gorm:"column:Name"`
+	*entity.SoftDelete
+}
+
+func (SoftDeletePtrTable) TableName() string { return "soft" }
+
+func TestDB_Delete_SoftDeleteColumn(t *testing.T) {
+	cases := []struct {
+		name    string
+		value   interface{}
+		wantSQL string
+		args    []driver.Value
+	}{
+		{
+			name:    "Delete_Slice_SoftDeletes",
+			value:   []*SoftDeleteTable{{Base: entity.NewBase(1)}, {Base: entity.NewBase(2)}},
+			wantSQL: "UPDATE `soft` SET `DeletedBy`=?,`DeletedDatim`=? WHERE `soft`.`DeletedDatim` IS NULL AND `Id` IN (?,?)",
+			args:    []driver.Value{uint(7), sqlmock.AnyArg(), 1, 2},
+		},
+		{
+			name:    "Delete_Value_SoftDeletes",
+			value:   SoftDeleteTable{Base: entity.NewBase(1)},
+			wantSQL: "UPDATE `soft` SET `DeletedBy`=?,`DeletedDatim`=? WHERE `soft`.`DeletedDatim` IS NULL AND `Id` = ?",
+			args:    []driver.Value{uint(7), sqlmock.AnyArg(), 1},
+		},
+		{
+			name:    "Delete_NilEmbedded_SoftDeletes",
+			value:   &SoftDeletePtrTable{Base: entity.NewBase(1)},
+			wantSQL: "UPDATE `soft` SET `DeletedBy`=?,`DeletedDatim`=? WHERE `soft`.`DeletedDatim` IS NULL AND `Id` = ?",
+			args:    []driver.Value{uint(7), sqlmock.AnyArg(), 1},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			dbMock, sqlMock := dbmocks.NewSqlMock()
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectExec(regexp.QuoteMeta(tt.wantSQL)).
+				WithArgs(tt.args...).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			sqlMock.ExpectCommit()
+
+			//When
+			err := db.Wrap(dbMock).Delete(db.WithAuditUser(context.Background(), 7), tt.value)
+
+			//Then
+			assert.NoError(t, err)
+			if err := sqlMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestDB_Delete_NoSoftDeleteColumn(t *testing.T) {
+	//Given
+	dbMock, sqlMock := dbmocks.NewSqlMock()
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(regexp.QuoteMeta("DELETE FROM `dummy` WHERE `dummy`.`Id` = ?")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit()
+
+	//When
+	err := db.Wrap(dbMock).Delete(context.Background(), &DummyTable{Base: entity.NewBase(1)})
+
+	//Then
+	assert.NoError(t, err)
+	if err := sqlMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDB_Upsert(t *testing.T) {
+	cases := []struct {
+		name          string
+		open          func() (*gorm.DB, sqlmock.Sqlmock)
+		updateColumns []string
+		fnMocks       func(sqlMock sqlmock.Sqlmock)
+		want          *db.UpsertResult
+		wantIDs       []uint
+	}{
+		{
+			name: "Upsert_MySQLSkipped_CountedByAffectedRows",
+			open: dbmocks.NewSqlMock,
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `dummy` (`Name`) VALUES (?),(?) ON DUPLICATE KEY UPDATE `Id`=`Id`")).
+					WithArgs("A", "B").
+					WillReturnResult(sqlmock.NewResult(2, 1))
+				sqlMock.ExpectCommit()
+			},
+			want: &db.UpsertResult{Inserted: 1, Updated: 1},
+		},
+		{
+			name:          "Upsert_MySQLUpdated_CountedByLockedRead",
+			open:          dbmocks.NewSqlMock,
+			updateColumns: []string{"Name"},
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `dummy` WHERE (`Name` = ? OR `Name` = ?) FOR UPDATE")).
+					WithArgs("A", "B").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				sqlMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `dummy` (`Name`) VALUES (?),(?) ON DUPLICATE KEY UPDATE `Name`=VALUES(`Name`)")).
+					WithArgs("A", "B").
+					WillReturnResult(sqlmock.NewResult(2, 3))
+				sqlMock.ExpectCommit()
+			},
+			want: &db.UpsertResult{Inserted: 1, Updated: 1},
+		},
+		{
+			name:          "Upsert_PostgresUpdated_CountedByXmax",
+			open:          newPostgresSqlMock,
+			updateColumns: []string{"Name"},
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "dummy" ("Name") VALUES ($1),($2) ON CONFLICT ("Name") DO UPDATE SET "Name"="excluded"."Name" RETURNING "Id",(xmax = 0)`)).
+					WithArgs("A", "B").
+					WillReturnRows(sqlmock.NewRows([]string{"Id", "?column?"}).AddRow(7, false).AddRow(8, true))
+				sqlMock.ExpectCommit()
+			},
+			want:    &db.UpsertResult{Inserted: 1, Updated: 1},
+			wantIDs: []uint{7, 8},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			dbMock, sqlMock := tt.open()
+			tt.fnMocks(sqlMock)
+			values := []*DummyTable{{Name: "A"}, {Name: "B"}}
+
+			//When
+			res, err := db.Wrap(dbMock).Upsert(context.Background(), values, []string{"Name"}, tt.updateColumns)
+
+			//Then
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+			for i, id := range tt.wantIDs {
+				assert.Equal(t, id, values[i].ID)
+			}
+			if err := sqlMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+// newPostgresSqlMock opens a PostgreSQL gorm DB over sqlmock.
+func newPostgresSqlMock() (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDb, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	conn, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDb}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Warn),
+	})
+	if err != nil {
+		panic(err)
+	}
+	return conn, mock
+}
+
+func TestNew_WaitForDB_Unreachable(t *testing.T) {
+	//Given
+	begin := time.Now()
+
+	//When
+	_, err := db.New(context.Background(), db.Port(1), db.DialTimeout(50*time.Millisecond), db.WaitForDB(600*time.Millisecond))
+
+	//Then
+	assert.Error(t, err)
+	assert.GreaterOrEqual(t, time.Since(begin), 600*time.Millisecond)
+}
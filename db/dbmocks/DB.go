@@ -5,10 +5,14 @@ package dbmocks
 import (
 	context "context"
 
+	sql "database/sql"
+
 	gorm "gorm.io/gorm"
 
 	mock "github.com/stretchr/testify/mock"
 
+	db "github.com/mauricetjmurphy/ms-common/db"
+
 	query "github.com/mauricetjmurphy/ms-common/db/query"
 )
 
@@ -31,6 +35,27 @@ func (_m *DB) Close() error {
 	return r0
 }
 
+// CreateInBatches provides a mock function with given fields: ctx, values
+func (_m *DB) CreateInBatches(ctx context.Context, values interface{}) (int64, error) {
+	ret := _m.Called(ctx, values)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) int64); ok {
+		r0 = rf(ctx, values)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(ctx, values)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DBInstance provides a mock function with given fields:
 func (_m *DB) DBInstance() *gorm.DB {
 	ret := _m.Called()
@@ -61,6 +86,43 @@ func (_m *DB) Delete(ctx context.Context, value interface{}) error {
 	return r0
 }
 
+// HardDelete provides a mock function with given fields: ctx, value
+func (_m *DB) HardDelete(ctx context.Context, value interface{}) error {
+	ret := _m.Called(ctx, value)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) error); ok {
+		r0 = rf(ctx, value)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Ping provides a mock function with given fields: ctx
+func (_m *DB) Ping(ctx context.Context) (*db.Health, error) {
+	ret := _m.Called(ctx)
+
+	var r0 *db.Health
+	if rf, ok := ret.Get(0).(func(context.Context) *db.Health); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*db.Health)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Query provides a mock function with given fields:
 func (_m *DB) Query() *query.Query {
 	ret := _m.Called()
@@ -77,6 +139,20 @@ func (_m *DB) Query() *query.Query {
 	return r0
 }
 
+// Restore provides a mock function with given fields: ctx, value
+func (_m *DB) Restore(ctx context.Context, value interface{}) error {
+	ret := _m.Called(ctx, value)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) error); ok {
+		r0 = rf(ctx, value)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Save provides a mock function with given fields: ctx, value
 func (_m *DB) Save(ctx context.Context, value interface{}) error {
 	ret := _m.Called(ctx, value)
@@ -91,6 +167,34 @@ func (_m *DB) Save(ctx context.Context, value interface{}) error {
 	return r0
 }
 
+// Stats provides a mock function with given fields:
+func (_m *DB) Stats() sql.DBStats {
+	ret := _m.Called()
+
+	var r0 sql.DBStats
+	if rf, ok := ret.Get(0).(func() sql.DBStats); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(sql.DBStats)
+	}
+
+	return r0
+}
+
+// Transaction provides a mock function with given fields: ctx, fn
+func (_m *DB) Transaction(ctx context.Context, fn func(db.DB) error) error {
+	ret := _m.Called(ctx, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(db.DB) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Update provides a mock function with given fields: ctx, value
 func (_m *DB) Update(ctx context.Context, value interface{}) error {
 	ret := _m.Called(ctx, value)
@@ -104,3 +208,26 @@ func (_m *DB) Update(ctx context.Context, value interface{}) error {
 
 	return r0
 }
+
+// Upsert provides a mock function with given fields: ctx, values, conflictColumns, updateColumns
+func (_m *DB) Upsert(ctx context.Context, values interface{}, conflictColumns []string, updateColumns []string) (*db.UpsertResult, error) {
+	ret := _m.Called(ctx, values, conflictColumns, updateColumns)
+
+	var r0 *db.UpsertResult
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, []string, []string) *db.UpsertResult); ok {
+		r0 = rf(ctx, values, conflictColumns, updateColumns)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*db.UpsertResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}, []string, []string) error); ok {
+		r1 = rf(ctx, values, conflictColumns, updateColumns)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
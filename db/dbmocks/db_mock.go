@@ -1,8 +1,12 @@
 package dbmocks
 
 import (
+	"context"
 	"database/sql"
+
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mauricetjmurphy/ms-common/db"
+	"github.com/stretchr/testify/mock"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -32,3 +36,12 @@ func NewSqlMock() (*gorm.DB, sqlmock.Sqlmock) {
 	}
 	return conn, mock
 }
+
+// MockTransaction expects a Transaction call on m and runs the given unit of work against tx,
+// so the repository code inside the transaction can be asserted through the tx mock.
+func MockTransaction(m *DB, tx db.DB) *mock.Call {
+	return m.On("Transaction", mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, fn func(db.DB) error) error {
+			return fn(tx)
+		})
+}
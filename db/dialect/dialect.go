@@ -0,0 +1,75 @@
+// Package dialect describes the SQL databases supported by db.New and db/migrate.
+//
+// MySQL is the default dialect. PostgreSQL and SQLite live in the postgres and sqlite
+// sub-packages, so their drivers are only linked into the services using them.
+package dialect
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"gorm.io/gorm"
+)
+
+// Conn includes the connection parameters of one database endpoint.
+type Conn struct {
+	Host     string
+	Port     int
+	Name     string
+	User     string
+	Password string
+
+	Loc          *time.Location
+	TLS          string
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	Params       map[string]string
+}
+
+// WithHost copies the connection parameters to the given host.
+func (c *Conn) WithHost(host string) *Conn {
+	cp := *c
+	cp.Host = host
+	return &cp
+}
+
+// Dialect opens the connections, the gorm sessions and the migration drivers of one SQL database.
+type Dialect interface {
+	// Name returns the dialect name.
+	Name() string
+	// DefaultPort returns the port the database listens on by default.
+	DefaultPort() int
+	// DSN builds the connection string on given connection parameters.
+	DSN(conn *Conn) string
+	// Connector returns the driver connector on given connection string.
+	Connector(dsn string) (driver.Connector, error)
+	// Dialector returns the gorm dialector on given connection string, using pool
+	// when not nil instead of opening a new one.
+	Dialector(dsn string, pool gorm.ConnPool) gorm.Dialector
+	// IsAuthError reports whether err is an authentication failure, e.g. on rotated credentials.
+	IsAuthError(err error) bool
+	// MigrateDriver returns the migration driver on given connection pool.
+	MigrateDriver(pool *sql.DB) (database.Driver, error)
+}
+
+// DriverConnector adapts a driver opening connections by DSN to a driver.Connector.
+func DriverConnector(d driver.Driver, dsn string) driver.Connector {
+	return &driverConnector{driver: d, dsn: dsn}
+}
+
+type driverConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c *driverConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *driverConnector) Driver() driver.Driver {
+	return c.driver
+}
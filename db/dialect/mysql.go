@@ -0,0 +1,77 @@
+package dialect
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/golang-migrate/migrate/v4/database"
+	migratemysql "github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/pkg/errors"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// errAccessDenied is the MySQL error number returned on authentication failures.
+const errAccessDenied = 1045
+
+type mysqlDialect struct{}
+
+// MySQL returns the MySQL and Aurora MySQL dialect.
+func MySQL() Dialect {
+	return mysqlDialect{}
+}
+
+func (mysqlDialect) Name() string {
+	return "mysql"
+}
+
+func (mysqlDialect) DefaultPort() int {
+	return 3306
+}
+
+func (mysqlDialect) DSN(conn *Conn) string {
+	cfg := mysqldriver.NewConfig()
+	cfg.User = conn.User
+	cfg.Passwd = conn.Password
+	cfg.Net = "tcp"
+	cfg.Addr = fmt.Sprintf("%s:%d", conn.Host, conn.Port)
+	cfg.DBName = conn.Name
+	cfg.MultiStatements = true
+	cfg.ParseTime = true
+	if conn.Loc != nil {
+		cfg.Loc = conn.Loc
+	}
+	cfg.TLSConfig = conn.TLS
+	cfg.Timeout = conn.DialTimeout
+	cfg.ReadTimeout = conn.ReadTimeout
+	cfg.WriteTimeout = conn.WriteTimeout
+	cfg.Params = conn.Params
+	return cfg.FormatDSN()
+}
+
+func (mysqlDialect) Connector(dsn string) (driver.Connector, error) {
+	cfg, err := mysqldriver.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return mysqldriver.NewConnector(cfg)
+}
+
+func (mysqlDialect) Dialector(dsn string, pool gorm.ConnPool) gorm.Dialector {
+	if pool == nil {
+		return mysql.Open(dsn)
+	}
+	cfg, _ := mysqldriver.ParseDSN(dsn)
+	return mysql.New(mysql.Config{DSN: dsn, DSNConfig: cfg, Conn: pool})
+}
+
+func (mysqlDialect) IsAuthError(err error) bool {
+	var mysqlErr *mysqldriver.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errAccessDenied
+}
+
+func (mysqlDialect) MigrateDriver(pool *sql.DB) (database.Driver, error) {
+	return migratemysql.WithInstance(pool, &migratemysql.Config{})
+}
@@ -0,0 +1,101 @@
+// Package postgres provides the PostgreSQL dialect of db.New and db/migrate.
+package postgres
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	migratepostgres "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/mauricetjmurphy/ms-common/db/dialect"
+	"github.com/pkg/errors"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	// errInvalidPassword and errInvalidAuthorization are the SQLSTATE codes of authentication failures.
+	errInvalidPassword      = "28P01"
+	errInvalidAuthorization = "28000"
+)
+
+type postgresDialect struct{}
+
+// Dialect returns the PostgreSQL and Aurora PostgreSQL dialect.
+//
+// The TLS mode maps to the sslmode parameter: true to require, skip-verify to require without
+// verification, false to disable; any other value is used as the sslmode itself. The read and
+// write timeouts are not supported by the driver and are ignored.
+func Dialect() dialect.Dialect {
+	return postgresDialect{}
+}
+
+func (postgresDialect) Name() string {
+	return "postgres"
+}
+
+func (postgresDialect) DefaultPort() int {
+	return 5432
+}
+
+func (postgresDialect) DSN(conn *dialect.Conn) string {
+	query := url.Values{}
+	for k, v := range conn.Params {
+		query.Set(k, v)
+	}
+	if mode := sslMode(conn.TLS); mode != "" {
+		query.Set("sslmode", mode)
+	}
+	if conn.Loc != nil {
+		query.Set("TimeZone", conn.Loc.String())
+	}
+	if conn.DialTimeout > 0 {
+		query.Set("connect_timeout", strconv.Itoa(int(conn.DialTimeout.Seconds())))
+	}
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(conn.User, conn.Password),
+		Host:     fmt.Sprintf("%s:%d", conn.Host, conn.Port),
+		Path:     conn.Name,
+		RawQuery: query.Encode(),
+	}
+	return dsn.String()
+}
+
+func (postgresDialect) Connector(dsn string) (driver.Connector, error) {
+	cfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return stdlib.GetConnector(*cfg), nil
+}
+
+func (postgresDialect) Dialector(dsn string, pool gorm.ConnPool) gorm.Dialector {
+	return postgres.New(postgres.Config{DSN: dsn, Conn: pool})
+}
+
+func (postgresDialect) IsAuthError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == errInvalidPassword || pgErr.Code == errInvalidAuthorization)
+}
+
+func (postgresDialect) MigrateDriver(pool *sql.DB) (database.Driver, error) {
+	return migratepostgres.WithInstance(pool, &migratepostgres.Config{})
+}
+
+func sslMode(tls string) string {
+	switch tls {
+	case "true", "skip-verify":
+		return "require"
+	case "false":
+		return "disable"
+	default:
+		return tls
+	}
+}
@@ -0,0 +1,65 @@
+// Package sqlite provides the embedded SQLite dialect of db.New and db/migrate,
+// mainly to run the repository tests without a database service.
+//
+// The driver requires cgo.
+package sqlite
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/url"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	sqlite3 "github.com/mattn/go-sqlite3"
+	"github.com/mauricetjmurphy/ms-common/db/dialect"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Memory is the database name of a private in-memory database. The database lives as long as
+// its connection, so db.MaxOpenConns(1) keeps it alive for the lifetime of the pool.
+const Memory = ":memory:"
+
+type sqliteDialect struct{}
+
+// Dialect returns the SQLite dialect. The database name is the path of the database file,
+// or Memory; the host, port and credentials are ignored.
+func Dialect() dialect.Dialect {
+	return sqliteDialect{}
+}
+
+func (sqliteDialect) Name() string {
+	return "sqlite"
+}
+
+func (sqliteDialect) DefaultPort() int {
+	return 0
+}
+
+func (sqliteDialect) DSN(conn *dialect.Conn) string {
+	if len(conn.Params) == 0 {
+		return conn.Name
+	}
+	query := url.Values{}
+	for k, v := range conn.Params {
+		query.Set(k, v)
+	}
+	return "file:" + conn.Name + "?" + query.Encode()
+}
+
+func (sqliteDialect) Connector(dsn string) (driver.Connector, error) {
+	return dialect.DriverConnector(&sqlite3.SQLiteDriver{}, dsn), nil
+}
+
+func (sqliteDialect) Dialector(dsn string, pool gorm.ConnPool) gorm.Dialector {
+	return &sqlite.Dialector{DSN: dsn, Conn: pool}
+}
+
+func (sqliteDialect) IsAuthError(error) bool {
+	return false
+}
+
+func (sqliteDialect) MigrateDriver(pool *sql.DB) (database.Driver, error) {
+	return migratesqlite.WithInstance(pool, &migratesqlite.Config{})
+}
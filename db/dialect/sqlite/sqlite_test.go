@@ -0,0 +1,177 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mauricetjmurphy/ms-common/db"
+	"github.com/mauricetjmurphy/ms-common/db/dialect/sqlite"
+	"github.com/mauricetjmurphy/ms-common/db/entity"
+	"github.com/mauricetjmurphy/ms-common/db/query/criteria"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type DummyTable struct {
+	*entity.Base
+	Name string `gorm:"column:Name"`
+}
+
+func (DummyTable) TableName() string { return "dummy" }
+
+func newDB(t *testing.T, opts ...db.Option) db.DB {
+	conn, err := db.New(context.Background(), append([]db.Option{
+		db.Dialect(sqlite.Dialect()),
+		db.Name(sqlite.Memory),
+		db.MaxOpenConns(1),
+	}, opts...)...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	require.NoError(t, conn.DBInstance().AutoMigrate(&DummyTable{}))
+	return conn
+}
+
+func TestDialect_SaveAndQuery(t *testing.T) {
+	//Given
+	conn := newDB(t)
+	require.NoError(t, conn.Save(context.Background(), &DummyTable{Name: "Test"}))
+	require.NoError(t, conn.Save(context.Background(), &DummyTable{Name: "Other"}))
+
+	//When
+	var results []*DummyTable
+	total, err := conn.Query().
+		Where(criteria.Eq("Name", "Test")).
+		Page(0, 10).
+		FindAll(context.Background(), &results)
+
+	//Then
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "Test", results[0].Name)
+	}
+}
+
+func TestDialect_Transaction_Rollback(t *testing.T) {
+	//Given
+	conn := newDB(t)
+	errRollback := errors.New("rollback")
+
+	//When
+	err := conn.Transaction(context.Background(), func(tx db.DB) error {
+		if err := tx.Save(context.Background(), &DummyTable{Name: "Test"}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+
+	//Then
+	assert.Equal(t, errRollback, err)
+	var results []*DummyTable
+	assert.NoError(t, conn.Query().Find(context.Background(), &results))
+	assert.Empty(t, results)
+}
+
+type RightsTable struct {
+	*entity.Base
+	Code   string `gorm:"column:Code;uniqueIndex"`
+	Status string `gorm:"column:Status"`
+}
+
+func (RightsTable) TableName() string { return "rights" }
+
+func TestDialect_CreateInBatches_Upsert(t *testing.T) {
+	//Given
+	conn := newDB(t, db.BatchSize(2))
+	require.NoError(t, conn.DBInstance().AutoMigrate(&RightsTable{}))
+	inserted, err := conn.CreateInBatches(context.Background(), []*RightsTable{
+		{Code: "A", Status: "Draft"},
+		{Code: "B", Status: "Draft"},
+		{Code: "C", Status: "Draft"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), inserted)
+
+	//When
+	res, err := conn.Upsert(context.Background(), []*RightsTable{
+		{Code: "B", Status: "Active"},
+		{Code: "D", Status: "Active"},
+		{Code: "C", Status: "Active"},
+	}, []string{"Code"}, []string{"Status"})
+
+	//Then
+	require.NoError(t, err)
+	assert.Equal(t, &db.UpsertResult{Inserted: 1, Updated: 2}, res)
+	var active []*RightsTable
+	require.NoError(t, conn.Query().Where(criteria.Eq("Status", "Active")).Find(context.Background(), &active))
+	assert.Len(t, active, 3)
+}
+
+func TestDialect_Metrics(t *testing.T) {
+	//Given
+	var observed []db.Statement
+	conn := newDB(t, db.Metrics(db.MetricsFunc(func(_ context.Context, stmt db.Statement) {
+		observed = append(observed, stmt)
+	})))
+	observed = nil
+
+	//When
+	require.NoError(t, conn.Save(context.Background(), &DummyTable{Name: "Test"}))
+	var results []*DummyTable
+	require.NoError(t, conn.Query().Find(context.Background(), &results))
+	queryErr := conn.DBInstance().Exec("SELECT * FROM missing").Error
+
+	//Then
+	assert.Error(t, queryErr)
+	if assert.Len(t, observed, 3) {
+		assert.Equal(t, "dummy", observed[0].Table)
+		assert.Equal(t, "create", observed[0].Operation)
+		assert.Equal(t, int64(1), observed[0].Rows)
+		assert.NoError(t, observed[0].Err)
+		assert.Equal(t, "dummy", observed[1].Table)
+		assert.Equal(t, "query", observed[1].Operation)
+		assert.Equal(t, "raw", observed[2].Operation)
+		assert.Error(t, observed[2].Err)
+	}
+}
+
+func TestDialect_Ping(t *testing.T) {
+	cases := []struct {
+		name        string
+		migrate     func(conn db.DB) error
+		wantVersion uint
+		wantDirty   bool
+	}{
+		{
+			name: "Ping_NotMigrated",
+		},
+		{
+			name: "Ping_Migrated",
+			migrate: func(conn db.DB) error {
+				return conn.DBInstance().Exec("CREATE TABLE schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL); " +
+					"INSERT INTO schema_migrations (version, dirty) VALUES (3, true)").Error
+			},
+			wantVersion: 3,
+			wantDirty:   true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			conn := newDB(t)
+			if tt.migrate != nil {
+				require.NoError(t, tt.migrate(conn))
+			}
+
+			//When
+			health, err := conn.Ping(context.Background())
+
+			//Then
+			require.NoError(t, err)
+			assert.Equal(t, 1, health.Pool.MaxOpenConnections)
+			assert.Equal(t, tt.wantVersion, health.MigrationVersion)
+			assert.Equal(t, tt.wantDirty, health.MigrationDirty)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package entity
+
+import "gorm.io/gorm"
+
+// DeletedDatimColumn and DeletedByColumn are the soft delete columns of SoftDelete.
+const (
+	DeletedDatimColumn = "DeletedDatim"
+	DeletedByColumn    = "DeletedBy"
+)
+
+// SoftDelete presents the soft delete column mapping. The entities embedding it are marked
+// deleted by db.DB.Delete instead of being removed, and the deleted rows are excluded from
+// the queries unless query.Query WithDeleted or OnlyDeleted is set.
+type SoftDelete struct {
+	DeletedDatim gorm.DeletedAt `gorm:"column:DeletedDatim;index"`
+	DeletedBy    *uint          `gorm:"column:DeletedBy"`
+}
+
+// SoftDeletable is implemented by the entities embedding SoftDelete.
+type SoftDeletable interface {
+	SoftDeleted() *SoftDelete
+}
+
+// SoftDeleted returns the soft delete columns of the entity.
+func (s *SoftDelete) SoftDeleted() *SoftDelete {
+	return s
+}
+
+// IsDeleted reports whether the entity is soft deleted.
+func (s *SoftDelete) IsDeleted() bool {
+	return s != nil && s.DeletedDatim.Valid
+}
@@ -0,0 +1,18 @@
+package entity
+
+// Version presents the optimistic locking column mapping. The entities embedding it are only
+// updated by db.DB.Update when their version is the stored one, and the version is incremented
+// on every update.
+type Version struct {
+	Version uint `gorm:"column:Version;not null;default:0"`
+}
+
+// Versioned is implemented by the entities embedding the optimistic locking Version.
+type Versioned interface {
+	LockVersion() *Version
+}
+
+// LockVersion returns the optimistic locking version of the entity.
+func (v *Version) LockVersion() *Version {
+	return v
+}
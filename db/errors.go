@@ -0,0 +1,42 @@
+package db
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// versionColumn is the optimistic locking column of entity.Version.
+const versionColumn = "Version"
+
+// ConflictError is returned by Update when the versioned entity was changed since it was read.
+type ConflictError struct {
+	// Table is the table of the entity.
+	Table string
+	// Version is the version of the entity the update was based on.
+	Version uint
+}
+
+// Error implements the error interface.
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("db : %v was modified concurrently, version %v is stale", e.Table, e.Version)
+}
+
+// GRPCStatus converts the conflict into the codes.Aborted status.
+func (e *ConflictError) GRPCStatus() *status.Status {
+	return status.New(codes.Aborted, e.Error())
+}
+
+// StatusCode is the HTTP status of the conflict.
+func (e *ConflictError) StatusCode() int {
+	return http.StatusConflict
+}
+
+// IsConflict reports whether err is or wraps a ConflictError.
+func IsConflict(err error) bool {
+	var conflict *ConflictError
+	return errors.As(err, &conflict)
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/mauricetjmurphy/ms-common/logx"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	// migrationsTable is the schema version table of the migrations.
+	migrationsTable = "schema_migrations"
+
+	waitMinBackoff = 250 * time.Millisecond
+	waitMaxBackoff = 5 * time.Second
+)
+
+// Health is the database health reported by Ping.
+type Health struct {
+	// Latency is the round trip of the ping.
+	Latency time.Duration
+	// Pool is the statistics of the primary connection pool.
+	Pool sql.DBStats
+	// Saturation is the ratio of the in use connections to the max open connections,
+	// zero when the pool is unbounded.
+	Saturation float64
+	// MigrationVersion is the applied schema version, zero when the database is not migrated.
+	MigrationVersion uint
+	// MigrationDirty reports whether the last migration failed, leaving the schema dirty.
+	MigrationDirty bool
+}
+
+// Ping checks the primary and the read replicas are reachable and reports the database health.
+// The health is returned along with the error of an unreachable database.
+func (db *dbImpl) Ping(ctx context.Context) (*Health, error) {
+	health := &Health{Pool: db.Stats()}
+	if max := health.Pool.MaxOpenConnections; max > 0 {
+		health.Saturation = float64(health.Pool.InUse) / float64(max)
+	}
+
+	begin := time.Now()
+	if err := db.ping(ctx); err != nil {
+		return health, errors.Wrap(err, "db : database is unreachable")
+	}
+	health.Latency = time.Since(begin)
+
+	// the schema version is read on the primary, the migrations being applied there first.
+	tx := db.withContext(ForcePrimary(ctx))
+	if !tx.Migrator().HasTable(migrationsTable) {
+		return health, nil
+	}
+	var version struct {
+		Version uint
+		Dirty   bool
+	}
+	if err := tx.Table(migrationsTable).Select("version, dirty").Limit(1).Scan(&version).Error; err != nil {
+		return health, errors.Wrap(err, "db : failed to read the schema version")
+	}
+	health.MigrationVersion, health.MigrationDirty = version.Version, version.Dirty
+	return health, nil
+}
+
+// ping pings the connection pools, the primary and the replicas ones when configured.
+func (db *dbImpl) ping(ctx context.Context) error {
+	if db.resolver != nil {
+		return db.resolver.Call(func(connPool gorm.ConnPool) error {
+			if pinger, ok := connPool.(interface{ PingContext(context.Context) error }); ok {
+				return pinger.PingContext(ctx)
+			}
+			return nil
+		})
+	}
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// waitForDB pings the database, retrying with backoff until it is reachable or the timeout is reached.
+func waitForDB(ctx context.Context, cfg *Config, dsn *dsnConf) error {
+	connector, err := cfg.Dialect.Connector(cfg.dsn(dsn))
+	if err != nil {
+		return errors.Wrap(err, "db : failed to create the connector")
+	}
+	pool := sql.OpenDB(connector)
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.WaitTimeout)
+	defer cancel()
+	backoff := waitMinBackoff
+	for {
+		err := pool.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		logx.Warnf("db : database is unreachable, retrying in %v: %v", backoff, err)
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(err, "db : database is unreachable after %v", cfg.WaitTimeout)
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > waitMaxBackoff {
+			backoff = waitMaxBackoff
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mauricetjmurphy/ms-common/db/dialect"
+	"github.com/stretchr/testify/assert"
+)
+
+// closeConn is a connection only supporting to be closed, enough to be pinged by the pool.
+type closeConn struct {
+	driver.Conn
+}
+
+func (closeConn) Close() error { return nil }
+
+// unreachableDialect refuses the connections until the database is up.
+type unreachableDialect struct {
+	dialect.Dialect
+	up       int
+	connects int
+}
+
+func (d *unreachableDialect) Connector(string) (driver.Connector, error) {
+	return d, nil
+}
+
+func (d *unreachableDialect) Connect(context.Context) (driver.Conn, error) {
+	if d.connects++; d.up <= 0 || d.connects < d.up {
+		return nil, errors.New("connection refused")
+	}
+	return closeConn{}, nil
+}
+
+func (d *unreachableDialect) Driver() driver.Driver { return nil }
+
+func TestWaitForDB(t *testing.T) {
+	cases := []struct {
+		name         string
+		up           int
+		timeout      time.Duration
+		wantErr      bool
+		wantConnects int
+	}{
+		{
+			name:         "WaitForDB_ReachableAfterRetries",
+			up:           3,
+			timeout:      5 * time.Second,
+			wantConnects: 3,
+		},
+		{
+			name:    "WaitForDB_Unreachable",
+			timeout: 300 * time.Millisecond,
+			wantErr: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			d := &unreachableDialect{Dialect: dialect.MySQL(), up: tt.up}
+			cfg := NewConfigs(Dialect(d), WaitForDB(tt.timeout))
+
+			//When
+			err := waitForDB(context.Background(), cfg, cfg.toDSN())
+
+			//Then
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantConnects, d.connects)
+		})
+	}
+}
@@ -0,0 +1,242 @@
+// Package lock provides the distributed locks shared by the instances of a service through its database.
+//
+// On MySQL the locks are the GET_LOCK named locks, each held lock taking a dedicated connection out
+// of the pool until Unlock: the pool MaxOpenConns must leave room for the locks held at once. A MySQL
+// lock has no expiry and the ttl only sets how often the lock is checked, the lock being released
+// by the database when its connection is closed.
+// On the other dialects the locks are the rows of the lock table, created by the service migrations:
+//
+//	CREATE TABLE "DistributedLock" (
+//	    "Name"         VARCHAR(255) PRIMARY KEY,
+//	    "Owner"        VARCHAR(64)  NOT NULL,
+//	    "ExpiresDatim" TIMESTAMP    NOT NULL
+//	);
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mauricetjmurphy/ms-common/db"
+	"github.com/mauricetjmurphy/ms-common/logx"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultTTL is the lease of a lock when none is given.
+	DefaultTTL = 30 * time.Second
+	// DefaultRetryInterval is the interval Lock retries to acquire a held lock.
+	DefaultRetryInterval = 500 * time.Millisecond
+)
+
+var (
+	// ErrNotAcquired is returned when the lock is held by another owner.
+	ErrNotAcquired = errors.New("lock : lock is held by another owner")
+	// errLost is returned by the lease renewal when the lock is no longer owned.
+	errLost = errors.New("lock : lock lost")
+)
+
+// Locker acquires the distributed locks.
+//
+//go:generate mockery --output lockmocks --outpkg lockmocks --name Locker
+type Locker interface {
+	// TryLock acquires the named lock, or returns ErrNotAcquired when it is held by another owner.
+	// The ttl is the lease renewed while the lock is held, see the package documentation on MySQL.
+	TryLock(ctx context.Context, name string, ttl time.Duration) (*Lock, error)
+	// Lock acquires the named lock, waiting for it to be released until the context is done.
+	Lock(ctx context.Context, name string, ttl time.Duration) (*Lock, error)
+}
+
+// Config presents the locker configuration.
+type Config struct {
+	// RetryInterval is the interval Lock retries to acquire a held lock.
+	RetryInterval time.Duration
+}
+
+// Option is a locker configuration option.
+type Option func(*Config)
+
+// RetryInterval provides the interval Lock retries to acquire a held lock.
+func RetryInterval(d time.Duration) Option {
+	return func(c *Config) {
+		c.RetryInterval = d
+	}
+}
+
+// backend acquires the leases of one dialect, a nil lease when the lock is held.
+type backend interface {
+	acquire(ctx context.Context, name string, ttl time.Duration) (lease, error)
+}
+
+// lease is one acquired lock.
+type lease interface {
+	// renew extends the lease by ttl, errLost when the lock is no longer owned.
+	renew(ctx context.Context, ttl time.Duration) error
+	release(ctx context.Context) error
+}
+
+type lockerImpl struct {
+	backend backend
+	cfg     *Config
+}
+
+// New creates the locker on given DB, using GET_LOCK on MySQL and the lock table on the other dialects.
+func New(conn db.DB, opts ...Option) (Locker, error) {
+	cfg := &Config{RetryInterval: DefaultRetryInterval}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	tx := conn.DBInstance()
+	if tx.Dialector.Name() != "mysql" {
+		return &lockerImpl{backend: newTableLocks(tx), cfg: cfg}, nil
+	}
+	pool, err := tx.DB()
+	if err != nil {
+		return nil, errors.Wrap(err, "lock : failed to get the connection pool")
+	}
+	return &lockerImpl{backend: &mysqlLocks{pool: pool}, cfg: cfg}, nil
+}
+
+func (l *lockerImpl) TryLock(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	ls, err := l.backend.acquire(ctx, name, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if ls == nil {
+		return nil, ErrNotAcquired
+	}
+	return newLock(ctx, name, ttl, ls), nil
+}
+
+func (l *lockerImpl) Lock(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	for {
+		lock, err := l.TryLock(ctx, name, ttl)
+		if err != ErrNotAcquired {
+			return lock, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(l.cfg.RetryInterval):
+		}
+	}
+}
+
+// WithLock runs fn while holding the named lock, or returns ErrNotAcquired without running it
+// when the lock is held by another owner. The context of fn is canceled if the lock is lost.
+func WithLock(ctx context.Context, locker Locker, name string, ttl time.Duration, fn func(ctx context.Context) error) error {
+	lock, err := locker.TryLock(ctx, name, ttl)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lock.Unlock(context.Background()); err != nil {
+			logx.Errorf("lock : failed to release the lock %v: %v", name, err)
+		}
+	}()
+	return fn(lock.Context())
+}
+
+// Lock is one acquired lock. The lease is renewed every third of its ttl until Unlock,
+// and the lock context is canceled when the lease cannot be renewed. Unlock must be called
+// once done, lost or not, to free the lease resources.
+type Lock struct {
+	name   string
+	ttl    time.Duration
+	lease  lease
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+
+	mu   sync.Mutex
+	lost bool
+}
+
+func newLock(ctx context.Context, name string, ttl time.Duration, ls lease) *Lock {
+	lockCtx, cancel := context.WithCancel(ctx)
+	lock := &Lock{
+		name:   name,
+		ttl:    ttl,
+		lease:  ls,
+		ctx:    lockCtx,
+		cancel: cancel,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go lock.keepAlive()
+	return lock
+}
+
+// Name returns the lock name.
+func (l *Lock) Name() string {
+	return l.name
+}
+
+// Context returns the context canceled when the lock is lost or released.
+func (l *Lock) Context() context.Context {
+	return l.ctx
+}
+
+// Lost reports whether the lock was lost before being released.
+func (l *Lock) Lost() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lost
+}
+
+// Unlock stops renewing the lease and releases the lock.
+func (l *Lock) Unlock(ctx context.Context) error {
+	var err error
+	l.once.Do(func() {
+		close(l.stop)
+		<-l.done
+		defer l.cancel()
+		rerr := l.lease.release(ctx)
+		// a lost lock has already been released by the database.
+		if !l.Lost() {
+			err = errors.Wrapf(rerr, "lock : failed to release the lock %v", l.name)
+		}
+	})
+	return err
+}
+
+// keepAlive renews the lease until Unlock. A failed renewal is retried on the next tick
+// and the lock is lost once the lease has expired without being renewed.
+func (l *Lock) keepAlive() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-l.ctx.Done():
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), l.ttl/3)
+			err := l.lease.renew(ctx, l.ttl)
+			cancel()
+			if err == nil {
+				renewed = time.Now()
+				continue
+			}
+			if err != errLost && time.Since(renewed) < l.ttl {
+				logx.Warnf("lock : failed to renew the lock %v: %v", l.name, err)
+				continue
+			}
+			logx.Errorf("lock : lock %v lost: %v", l.name, err)
+			l.mu.Lock()
+			l.lost = true
+			l.mu.Unlock()
+			l.cancel()
+			return
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mauricetjmurphy/ms-common/db"
+	"github.com/mauricetjmurphy/ms-common/db/dbmocks"
+	"github.com/mauricetjmurphy/ms-common/db/dialect/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTableLocker(t *testing.T) (Locker, db.DB) {
+	conn, err := db.New(context.Background(),
+		db.Dialect(sqlite.Dialect()),
+		db.Name(sqlite.Memory),
+		db.MaxOpenConns(1),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	require.NoError(t, conn.DBInstance().AutoMigrate(&Record{}))
+	locker, err := New(conn, RetryInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	return locker, conn
+}
+
+func TestLocker_TryLock_Table(t *testing.T) {
+	//Given
+	locker, _ := newTableLocker(t)
+	held, err := locker.TryLock(context.Background(), "job", time.Minute)
+	require.NoError(t, err)
+
+	//When
+	_, heldErr := locker.TryLock(context.Background(), "job", time.Minute)
+	other, otherErr := locker.TryLock(context.Background(), "other", time.Minute)
+	require.NoError(t, held.Unlock(context.Background()))
+	released, releasedErr := locker.TryLock(context.Background(), "job", time.Minute)
+
+	//Then
+	assert.Equal(t, ErrNotAcquired, heldErr)
+	assert.NoError(t, otherErr)
+	assert.NoError(t, releasedErr)
+	assert.Equal(t, context.Canceled, held.Context().Err())
+	assert.NoError(t, other.Unlock(context.Background()))
+	assert.NoError(t, released.Unlock(context.Background()))
+}
+
+func TestLocker_TryLock_Table_Expired(t *testing.T) {
+	//Given
+	locker, conn := newTableLocker(t)
+	require.NoError(t, conn.DBInstance().Create(&Record{Name: "job", Owner: "crashed", ExpiresDatim: time.Now().Add(-time.Second)}).Error)
+
+	//When
+	lock, err := locker.TryLock(context.Background(), "job", time.Minute)
+
+	//Then
+	require.NoError(t, err)
+	assert.NoError(t, lock.Unlock(context.Background()))
+}
+
+func TestLocker_Lock_Waits(t *testing.T) {
+	//Given
+	locker, _ := newTableLocker(t)
+	held, err := locker.TryLock(context.Background(), "job", time.Minute)
+	require.NoError(t, err)
+	time.AfterFunc(50*time.Millisecond, func() { _ = held.Unlock(context.Background()) })
+
+	//When
+	lock, err := locker.Lock(context.Background(), "job", time.Minute)
+
+	//Then
+	require.NoError(t, err)
+	assert.NoError(t, lock.Unlock(context.Background()))
+}
+
+func TestLocker_Lock_ContextDone(t *testing.T) {
+	//Given
+	locker, _ := newTableLocker(t)
+	held, err := locker.TryLock(context.Background(), "job", time.Minute)
+	require.NoError(t, err)
+	defer held.Unlock(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	//When
+	_, err = locker.Lock(ctx, "job", time.Minute)
+
+	//Then
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestLock_Lost(t *testing.T) {
+	//Given
+	locker, conn := newTableLocker(t)
+	lock, err := locker.TryLock(context.Background(), "job", 60*time.Millisecond)
+	require.NoError(t, err)
+
+	//When
+	require.NoError(t, conn.DBInstance().Where("Name = ?", "job").Delete(&Record{}).Error)
+
+	//Then
+	select {
+	case <-lock.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("lock context not canceled on the lost lock")
+	}
+	assert.True(t, lock.Lost())
+	assert.NoError(t, lock.Unlock(context.Background()))
+}
+
+func TestWithLock(t *testing.T) {
+	errJob := errors.New("job failed")
+
+	cases := []struct {
+		name    string
+		held    bool
+		fnErr   error
+		wantRun bool
+		wantErr error
+	}{
+		{
+			name:    "WithLock_Runs",
+			wantRun: true,
+		},
+		{
+			name:    "WithLock_ReturnsFnError",
+			fnErr:   errJob,
+			wantRun: true,
+			wantErr: errJob,
+		},
+		{
+			name:    "WithLock_Held_Skipped",
+			held:    true,
+			wantErr: ErrNotAcquired,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			locker, _ := newTableLocker(t)
+			if tt.held {
+				held, err := locker.TryLock(context.Background(), "job", time.Minute)
+				require.NoError(t, err)
+				defer held.Unlock(context.Background())
+			}
+
+			//When
+			var run bool
+			err := WithLock(context.Background(), locker, "job", time.Minute, func(ctx context.Context) error {
+				run = true
+				assert.NoError(t, ctx.Err())
+				return tt.fnErr
+			})
+
+			//Then
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantRun, run)
+		})
+	}
+}
+
+func TestLocker_TryLock_MySQL(t *testing.T) {
+	cases := []struct {
+		name    string
+		fnMocks func(sqlMock sqlmock.Sqlmock)
+		wantErr error
+	}{
+		{
+			name: "TryLock_Acquired",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT GET_LOCK(?, 0)")).
+					WithArgs("job").
+					WillReturnRows(sqlmock.NewRows([]string{"GET_LOCK"}).AddRow(1))
+				sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT RELEASE_LOCK(?)")).
+					WithArgs("job").
+					WillReturnRows(sqlmock.NewRows([]string{"RELEASE_LOCK"}).AddRow(1))
+			},
+		},
+		{
+			name: "TryLock_Held",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT GET_LOCK(?, 0)")).
+					WithArgs("job").
+					WillReturnRows(sqlmock.NewRows([]string{"GET_LOCK"}).AddRow(0))
+			},
+			wantErr: ErrNotAcquired,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			mockDB, sqlMock := dbmocks.NewSqlMock()
+			tt.fnMocks(sqlMock)
+			locker, err := New(db.Wrap(mockDB))
+			require.NoError(t, err)
+
+			//When
+			lock, err := locker.TryLock(context.Background(), "job", time.Minute)
+
+			//Then
+			assert.Equal(t, tt.wantErr, err)
+			if lock != nil {
+				assert.NoError(t, lock.Unlock(context.Background()))
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTableLocks_QuotedColumns(t *testing.T) {
+	//Given
+	mockDB, sqlMock := dbmocks.NewSqlMock()
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(regexp.QuoteMeta("DELETE FROM `DistributedLock` WHERE `Name` = ? AND `ExpiresDatim` < ?")).
+		WithArgs("job", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectCommit()
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `DistributedLock`")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit()
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(regexp.QuoteMeta("UPDATE `DistributedLock` SET `ExpiresDatim`=? WHERE `Name` = ? AND `Owner` = ?")).
+		WithArgs(sqlmock.AnyArg(), "job", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit()
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(regexp.QuoteMeta("DELETE FROM `DistributedLock` WHERE `Name` = ? AND `Owner` = ?")).
+		WithArgs("job", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit()
+	locks := newTableLocks(mockDB)
+
+	//When
+	ls, err := locks.acquire(context.Background(), "job", time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, ls)
+	renewErr := ls.renew(context.Background(), time.Minute)
+	releaseErr := ls.release(context.Background())
+
+	//Then
+	assert.NoError(t, renewErr)
+	assert.NoError(t, releaseErr)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
@@ -0,0 +1,58 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// mysqlLocks acquires the GET_LOCK named locks, each lock holding its own connection
+// as MySQL releases the named locks of a closed session.
+type mysqlLocks struct {
+	pool *sql.DB
+}
+
+func (m *mysqlLocks) acquire(ctx context.Context, name string, _ time.Duration) (lease, error) {
+	conn, err := m.pool.Conn(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "lock : failed to open the lock connection")
+	}
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", name).Scan(&acquired); err != nil {
+		_ = conn.Close()
+		return nil, errors.Wrapf(err, "lock : failed to acquire the lock %v", name)
+	}
+	if acquired.Int64 != 1 {
+		_ = conn.Close()
+		return nil, nil
+	}
+	return &mysqlLease{conn: conn, name: name}, nil
+}
+
+type mysqlLease struct {
+	conn *sql.Conn
+	name string
+}
+
+// renew checks the lock is still held by the session, the lock has no expiry while its session lives.
+func (m *mysqlLease) renew(ctx context.Context, _ time.Duration) error {
+	var owned sql.NullInt64
+	if err := m.conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?) = CONNECTION_ID()", m.name).Scan(&owned); err != nil {
+		if err == sql.ErrConnDone {
+			return errLost
+		}
+		return err
+	}
+	if owned.Int64 != 1 {
+		return errLost
+	}
+	return nil
+}
+
+func (m *mysqlLease) release(ctx context.Context) error {
+	defer m.conn.Close()
+	var released sql.NullInt64
+	return m.conn.QueryRowContext(ctx, "SELECT RELEASE_LOCK(?)", m.name).Scan(&released)
+}
@@ -0,0 +1,84 @@
+package lock
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	nameColumn    = "Name"
+	ownerColumn   = "Owner"
+	expiresColumn = "ExpiresDatim"
+)
+
+// Record is one lock of the lock table.
+type Record struct {
+	Name string `gorm:"column:Name;primaryKey;size:255"`
+	// Owner identifies the lease holding the lock.
+	Owner        string    `gorm:"column:Owner;size:64;not null"`
+	ExpiresDatim time.Time `gorm:"column:ExpiresDatim;not null"`
+}
+
+// TableName returns the lock table name.
+func (Record) TableName() string { return "DistributedLock" }
+
+// tableLocks acquires the locks as the rows of the lock table, an expired row being taken over
+// by the next owner. The expiry is on the instances' clocks.
+type tableLocks struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+func newTableLocks(db *gorm.DB) *tableLocks {
+	return &tableLocks{db: db, now: time.Now}
+}
+
+func (t *tableLocks) acquire(ctx context.Context, name string, ttl time.Duration) (lease, error) {
+	tx := t.db.WithContext(ctx)
+	now := t.now()
+	if err := tx.Where(clause.Eq{Column: nameColumn, Value: name}, clause.Lt{Column: expiresColumn, Value: now}).
+		Delete(&Record{}).Error; err != nil {
+		return nil, errors.Wrapf(err, "lock : failed to delete the expired lock %v", name)
+	}
+	record := &Record{Name: name, Owner: uuid.NewString(), ExpiresDatim: now.Add(ttl)}
+	results := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if results.Error != nil {
+		return nil, errors.Wrapf(results.Error, "lock : failed to acquire the lock %v", name)
+	}
+	if results.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &tableLease{locks: t, name: name, owner: record.Owner}, nil
+}
+
+type tableLease struct {
+	locks *tableLocks
+	name  string
+	owner string
+}
+
+func (t *tableLease) renew(ctx context.Context, ttl time.Duration) error {
+	results := t.owned(ctx).Update(expiresColumn, t.locks.now().Add(ttl))
+	if results.Error != nil {
+		return results.Error
+	}
+	if results.RowsAffected == 0 {
+		return errLost
+	}
+	return nil
+}
+
+func (t *tableLease) release(ctx context.Context) error {
+	return t.owned(ctx).Delete(&Record{}).Error
+}
+
+// owned selects the lock row while owned by the lease.
+func (t *tableLease) owned(ctx context.Context) *gorm.DB {
+	return t.locks.db.WithContext(ctx).Model(&Record{}).
+		Where(clause.Eq{Column: nameColumn, Value: t.name}, clause.Eq{Column: ownerColumn, Value: t.owner})
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mauricetjmurphy/ms-common/logx"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// DefaultSlowThreshold is the default duration above which a statement is logged as slow.
+const DefaultSlowThreshold = 200 * time.Millisecond
+
+// sourceDir is the directory of the db packages, skipped when resolving the statement caller.
+var sourceDir string
+
+func init() {
+	_, file, _, _ := runtime.Caller(0)
+	sourceDir = filepath.Dir(file) + string(filepath.Separator)
+}
+
+// dbLogger logs the statements through logx: the errors at error level, the statements slower than
+// the slow threshold at warn level with their caller, and every statement at debug level.
+type dbLogger struct {
+	level         logger.LogLevel
+	slowThreshold time.Duration
+	logParams     bool
+}
+
+func newLogger(cfg *Config) *dbLogger {
+	return &dbLogger{
+		level:         cfg.LogLevel,
+		slowThreshold: cfg.SlowThreshold,
+		logParams:     cfg.LogParams,
+	}
+}
+
+func (l *dbLogger) LogMode(level logger.LogLevel) logger.Interface {
+	cp := *l
+	cp.level = level
+	return &cp
+}
+
+func (l *dbLogger) Info(ctx context.Context, s string, args ...interface{}) {
+	logx.WithContext(ctx).Infof(s, args...)
+}
+
+func (l *dbLogger) Warn(ctx context.Context, s string, args ...interface{}) {
+	logx.WithContext(ctx).Warnf(s, args...)
+}
+
+func (l *dbLogger) Error(ctx context.Context, s string, args ...interface{}) {
+	logx.WithContext(ctx).Errorf(s, args...)
+}
+
+// ParamsFilter redacts the bound parameters of the logged statements unless LogParams is set.
+func (l *dbLogger) ParamsFilter(_ context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.logParams {
+		return sql, params
+	}
+	return sql, nil
+}
+
+func (l *dbLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	slow := l.slowThreshold > 0 && elapsed > l.slowThreshold
+	switch {
+	case failed && l.level >= logger.Error:
+		sql, rows := fc()
+		l.entry(ctx, rows, elapsed, err).Errorf("db : statement failed %s [%s]", sql, elapsed)
+	case slow && l.level >= logger.Warn:
+		sql, rows := fc()
+		l.entry(ctx, rows, elapsed, err).Warnf("db : slow statement %s [%s]", sql, elapsed)
+	case l.level >= logger.Info:
+		sql, rows := fc()
+		fields := logx.Fields{
+			"rows": rows,
+		}
+		if err != nil {
+			fields["error"] = err
+		}
+		logx.WithContext(ctx).WithFields(logrus.Fields(fields)).Debugf("%s [%s]", sql, elapsed)
+	}
+}
+
+func (l *dbLogger) entry(ctx context.Context, rows int64, elapsed time.Duration, err error) *logrus.Entry {
+	fields := logx.Fields{
+		"rows":     rows,
+		"duration": elapsed.String(),
+		"caller":   caller(),
+	}
+	if err != nil {
+		fields["error"] = err
+	}
+	return logx.WithContext(ctx).WithFields(logrus.Fields(fields))
+}
+
+// caller returns the file and line of the first caller outside of gorm and the db packages.
+func caller() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		internal := strings.Contains(frame.File, "gorm.io/") ||
+			(strings.HasPrefix(frame.File, sourceDir) && !strings.HasSuffix(frame.File, "_test.go"))
+		if !internal {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
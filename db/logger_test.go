@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mauricetjmurphy/ms-common/logx"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// entriesHook records the logged entries.
+type entriesHook struct {
+	entries []*logrus.Entry
+}
+
+func (h *entriesHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (h *entriesHook) Fire(entry *logrus.Entry) error {
+	h.entries = append(h.entries, entry)
+	return nil
+}
+
+func captureLogs(t *testing.T) *entriesHook {
+	hook := &entriesHook{}
+	std := logrus.StandardLogger()
+	level, hooks := std.GetLevel(), std.ReplaceHooks(logrus.LevelHooks{})
+	std.SetLevel(logrus.DebugLevel)
+	std.AddHook(hook)
+	t.Cleanup(func() {
+		std.SetLevel(level)
+		std.ReplaceHooks(hooks)
+	})
+	return hook
+}
+
+func TestDBLogger_Trace(t *testing.T) {
+	errFailed := errors.New("deadlock")
+
+	cases := []struct {
+		name      string
+		opts      []Option
+		elapsed   time.Duration
+		err       error
+		wantLevel logrus.Level
+		wantMsg   string
+		wantNone  bool
+	}{
+		{
+			name:      "Trace_Statement_Debug",
+			wantLevel: logrus.DebugLevel,
+			wantMsg:   "SELECT * FROM dummy WHERE Name = ?",
+		},
+		{
+			name:      "Trace_RecordNotFound_Debug",
+			err:       gorm.ErrRecordNotFound,
+			wantLevel: logrus.DebugLevel,
+			wantMsg:   "SELECT * FROM dummy WHERE Name = ?",
+		},
+		{
+			name:      "Trace_Failed_Error",
+			err:       errFailed,
+			wantLevel: logrus.ErrorLevel,
+			wantMsg:   "db : statement failed SELECT * FROM dummy WHERE Name = ?",
+		},
+		{
+			name:      "Trace_Slow_Warn",
+			elapsed:   time.Second,
+			wantLevel: logrus.WarnLevel,
+			wantMsg:   "db : slow statement SELECT * FROM dummy WHERE Name = ?",
+		},
+		{
+			name:      "Trace_Slow_Disabled_Debug",
+			opts:      []Option{SlowThreshold(0)},
+			elapsed:   time.Second,
+			wantLevel: logrus.DebugLevel,
+			wantMsg:   "SELECT * FROM dummy WHERE Name = ?",
+		},
+		{
+			name:      "Trace_LogParams",
+			opts:      []Option{LogParams(true)},
+			wantLevel: logrus.DebugLevel,
+			wantMsg:   "SELECT * FROM dummy WHERE Name = \"secret\"",
+		},
+		{
+			name:     "Trace_WarnLevel_SkipsStatements",
+			opts:     []Option{LogLevel(logger.Warn)},
+			wantNone: true,
+		},
+		{
+			name:     "Trace_Silent",
+			opts:     []Option{LogLevel(logger.Silent)},
+			err:      errFailed,
+			wantNone: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			hook := captureLogs(t)
+			l := newLogger(NewConfigs(tt.opts...))
+			begin := time.Now().Add(-tt.elapsed)
+
+			//When
+			l.Trace(context.Background(), begin, func() (string, int64) {
+				sql, vars := l.ParamsFilter(context.Background(), "SELECT * FROM dummy WHERE Name = ?", "secret")
+				return logger.ExplainSQL(sql, nil, `"`, vars...), 1
+			}, tt.err)
+
+			//Then
+			if tt.wantNone {
+				assert.Empty(t, hook.entries)
+				return
+			}
+			if assert.Len(t, hook.entries, 1) {
+				entry := hook.entries[0]
+				assert.Equal(t, tt.wantLevel, entry.Level)
+				assert.Contains(t, entry.Message, tt.wantMsg)
+				assert.Equal(t, int64(1), entry.Data["rows"])
+				if tt.wantLevel != logrus.DebugLevel {
+					assert.Contains(t, entry.Data["caller"], "logger_test.go")
+				}
+			}
+		})
+	}
+}
+
+func TestDBLogger_Trace_ContextFields(t *testing.T) {
+	//Given
+	hook := captureLogs(t)
+	l := newLogger(NewConfigs(SlowThreshold(time.Millisecond)))
+	ctx := logx.ContextWithFields(context.Background(), logx.Fields{"requestId": "req-1"})
+
+	//When
+	l.Trace(ctx, time.Now(), func() (string, int64) { return "SELECT 1", 1 }, nil)
+	l.Trace(ctx, time.Now().Add(-time.Second), func() (string, int64) { return "SELECT 1", 1 }, nil)
+	l.Trace(ctx, time.Now(), func() (string, int64) { return "SELECT 1", 0 }, errors.New("deadlock"))
+
+	//Then
+	if assert.Len(t, hook.entries, 3) {
+		for _, entry := range hook.entries {
+			assert.Equal(t, "req-1", entry.Data["requestId"])
+			assert.Equal(t, ctx, entry.Context)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const metricsStartKey = "db:metrics_start"
+
+// Statement is the metric of one executed statement.
+type Statement struct {
+	// Table is the statement table, empty on the raw statements without model.
+	Table string
+	// Operation is the statement operation: create, query, update, delete, row or raw.
+	Operation string
+	Duration  time.Duration
+	Rows      int64
+	// Err is the statement error, gorm.ErrRecordNotFound excluded.
+	Err error
+}
+
+// MetricsSink receives the metrics of the executed statements, e.g. to count them and
+// record their latency and errors by table and operation.
+type MetricsSink interface {
+	ObserveStatement(ctx context.Context, stmt Statement)
+}
+
+// MetricsFunc adapts a function to the MetricsSink.
+type MetricsFunc func(ctx context.Context, stmt Statement)
+
+func (f MetricsFunc) ObserveStatement(ctx context.Context, stmt Statement) {
+	f(ctx, stmt)
+}
+
+// useMetrics registers the callbacks timing the statements of every operation into the sink.
+func useMetrics(tx *gorm.DB, sink MetricsSink) error {
+	cb := tx.Callback()
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("db:metrics_start_create", startMetrics),
+		cb.Create().After("gorm:create").Register("db:metrics_create", observeMetrics(sink, "create")),
+		cb.Query().Before("gorm:query").Register("db:metrics_start_query", startMetrics),
+		cb.Query().After("gorm:query").Register("db:metrics_query", observeMetrics(sink, "query")),
+		cb.Update().Before("gorm:update").Register("db:metrics_start_update", startMetrics),
+		cb.Update().After("gorm:update").Register("db:metrics_update", observeMetrics(sink, "update")),
+		cb.Delete().Before("gorm:delete").Register("db:metrics_start_delete", startMetrics),
+		cb.Delete().After("gorm:delete").Register("db:metrics_delete", observeMetrics(sink, "delete")),
+		cb.Row().Before("gorm:row").Register("db:metrics_start_row", startMetrics),
+		cb.Row().After("gorm:row").Register("db:metrics_row", observeMetrics(sink, "row")),
+		cb.Raw().Before("gorm:raw").Register("db:metrics_start_raw", startMetrics),
+		cb.Raw().After("gorm:raw").Register("db:metrics_raw", observeMetrics(sink, "raw")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startMetrics(tx *gorm.DB) {
+	tx.InstanceSet(metricsStartKey, time.Now())
+}
+
+func observeMetrics(sink MetricsSink, operation string) func(tx *gorm.DB) {
+	return func(tx *gorm.DB) {
+		value, ok := tx.InstanceGet(metricsStartKey)
+		if !ok {
+			return
+		}
+		stmt := Statement{
+			Table:     tx.Statement.Table,
+			Operation: operation,
+			Duration:  time.Since(value.(time.Time)),
+			Rows:      tx.RowsAffected,
+		}
+		if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+			stmt.Err = tx.Error
+		}
+		sink.ObserveStatement(tx.Statement.Context, stmt)
+	}
+}
@@ -2,122 +2,417 @@ package migrate
 
 import (
 	"database/sql"
+	"fmt"
+	"io/fs"
+	"os"
 
 	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/mauricetjmurphy/ms-common/db/dialect"
 	"github.com/mauricetjmurphy/ms-common/logx"
 	"github.com/pkg/errors"
 
 	// register migration from source file
-	_ "github.com/golang-migrate/migrate/source/file"
-)
-
-const (
-	driver = "mysql"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
 //go:generate mockery --output migratesmocks --outpkg migratesmocks --name Migrator
 type Migrator interface {
+	// Migrate applies all the pending migrations.
 	Migrate() error
+	// Up applies all the pending migrations, returning the planned steps.
+	Up() ([]Step, error)
+	// Down reverts the n last applied migrations, returning the planned steps.
+	Down(n int) ([]Step, error)
+	// Goto migrates up or down to given version, returning the planned steps.
+	Goto(version uint) ([]Step, error)
+	// Force sets the schema version without migrating and clears the dirty flag,
+	// -1 removes the version.
+	Force(version int) error
+	// Status reports the current schema version and the applied and pending migrations.
+	Status() (*Status, error)
 }
 
+// DirtyPolicy is the recovery of a dirty schema version, left by a failed migration.
+type DirtyPolicy int
+
+const (
+	// DirtyFail fails on a dirty schema version, the version must be fixed manually.
+	DirtyFail DirtyPolicy = iota
+	// DirtyRetry forces the version prior to the dirty one to re-attempt the failed migration.
+	DirtyRetry
+	// DirtyAccept forces the dirty version as applied, when the failed migration was completed manually.
+	DirtyAccept
+)
+
 type Options struct {
 	DSN       string
 	DBName    string
 	SourceURL string
+	// SourceFS is the file system holding the migrations, e.g. an embed.FS compiled into the binary.
+	// SourceURL is then the directory of the migrations in SourceFS, defaults to its root.
+	SourceFS fs.FS
+	// Dialect is the SQL database to migrate, defaults to MySQL.
+	Dialect dialect.Dialect
+	// DirtyPolicy is the recovery of a dirty schema version, defaults to DirtyFail.
+	DirtyPolicy DirtyPolicy
+	// DryRun plans the migrations of Migrate, Up, Down and Goto, and the recovery of a dirty version,
+	// without applying them.
+	DryRun bool
+}
+
+// Migration is a migration of the source.
+type Migration struct {
+	Version    uint
+	Identifier string
+}
+
+// Step is a planned migration.
+type Step struct {
+	Migration
+	Direction source.Direction
+}
+
+func (s Step) String() string {
+	return fmt.Sprintf("%v_%v.%v", s.Version, s.Identifier, s.Direction)
+}
+
+// Status is the migration status of the database.
+type Status struct {
+	// Version is the current schema version, 0 when no migration is applied.
+	Version uint
+	// Dirty reports the current version failed to migrate.
+	Dirty bool
+	// Applied are the source migrations up to the current version.
+	Applied []Migration
+	// Pending are the source migrations after the current version.
+	Pending []Migration
 }
 
 type migratorImpl struct {
-	DNS        string
-	DBName     string
-	SourceURL  string
-	SQLMigrate *migrate.Migrate
-	Logger     migrate.Logger
+	DNS         string
+	DBName      string
+	SourceURL   string
+	SourceFS    fs.FS
+	Dialect     dialect.Dialect
+	DirtyPolicy DirtyPolicy
+	DryRun      bool
+	SQLMigrate  *migrate.Migrate
+	Logger      migrate.Logger
+
+	source source.Driver
 }
 
 func New(config Options) (Migrator, error) {
 	mi := &migratorImpl{
-		DNS:       config.DSN,
-		DBName:    config.DBName,
-		SourceURL: config.SourceURL,
+		DNS:         config.DSN,
+		DBName:      config.DBName,
+		SourceURL:   config.SourceURL,
+		SourceFS:    config.SourceFS,
+		Dialect:     config.Dialect,
+		DirtyPolicy: config.DirtyPolicy,
+		DryRun:      config.DryRun,
+	}
+	if mi.Dialect == nil {
+		mi.Dialect = dialect.MySQL()
 	}
 	mi.Logger = mi
 	return mi, nil
 }
 
 func (mi *migratorImpl) Migrate() error {
+	_, err := mi.Up()
+	return err
+}
+
+func (mi *migratorImpl) Up() ([]Step, error) {
+	return mi.run("up", func(m *migrate.Migrate, version uint, hasVersion bool) ([]Step, error) {
+		return mi.plan(version, hasVersion, nil)
+	}, func(m *migrate.Migrate) error {
+		return m.Up()
+	})
+}
+
+func (mi *migratorImpl) Down(n int) ([]Step, error) {
+	if n <= 0 {
+		return nil, errors.Errorf("migrate : invalid number of migrations to revert %v", n)
+	}
+	return mi.run("down", func(m *migrate.Migrate, version uint, hasVersion bool) ([]Step, error) {
+		steps, err := mi.plan(version, hasVersion, new(uint))
+		if err != nil {
+			return nil, err
+		}
+		if len(steps) < n {
+			return nil, errors.Errorf("migrate : cannot revert %v migrations, %v applied", n, len(steps))
+		}
+		return steps[:n], nil
+	}, func(m *migrate.Migrate) error {
+		return m.Steps(-n)
+	})
+}
+
+func (mi *migratorImpl) Goto(version uint) ([]Step, error) {
+	return mi.run("goto", func(m *migrate.Migrate, current uint, hasVersion bool) ([]Step, error) {
+		if _, err := mi.identifier(version); err != nil {
+			return nil, errors.Wrapf(err, "migrate : unknown migration version %v", version)
+		}
+		return mi.plan(current, hasVersion, &version)
+	}, func(m *migrate.Migrate) error {
+		return m.Migrate(version)
+	})
+}
+
+func (mi *migratorImpl) Force(version int) error {
 	if mi == nil {
 		return errors.New("migrate: migration is null")
 	}
-	session, err := sql.Open(driver, mi.DNS)
+	m, err := mi.open()
 	if err != nil {
-		return errors.Wrap(err, "db: failed to open connection")
+		return err
 	}
+	defer mi.close(m)
+
+	if mi.DryRun {
+		m.Log.Printf("migrate : dry run, version not forced to %v", version)
+		return nil
+	}
+	if err := m.Force(version); err != nil {
+		return errors.Wrapf(err, "migrate : failed to force to schema version %v", version)
+	}
+	m.Log.Printf("migrate : forced to schema version %v", version)
+	return nil
+}
 
-	driver, err := mysql.WithInstance(session, &mysql.Config{})
+func (mi *migratorImpl) Status() (*Status, error) {
+	if mi == nil {
+		return nil, errors.New("migrate: migration is null")
+	}
+	m, err := mi.open()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer func() {
-		if err := driver.Close(); err != nil {
-			return
-		}
-	}()
+	defer mi.close(m)
 
-	m, err := migrate.NewWithDatabaseInstance(mi.SourceURL, mi.DBName, driver)
+	version, dirty, err := m.Version()
+	if err != nil && err != migrate.ErrNilVersion {
+		return nil, errors.Wrap(err, "migrate : failed to get migration version")
+	}
+	hasVersion := err == nil
+	migrations, err := mi.migrations()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	status := &Status{Version: version, Dirty: dirty}
+	for _, migration := range migrations {
+		if hasVersion && migration.Version <= version {
+			status.Applied = append(status.Applied, migration)
+		} else {
+			status.Pending = append(status.Pending, migration)
+		}
 	}
+	return status, nil
+}
 
-	m.Log = mi.Logger
-	mi.SQLMigrate = m
+// run opens the migration, recovers the dirty version on the policy and applies the planned
+// migrations, returning the planned steps. On dry run, the recovery and the steps are only planned.
+func (mi *migratorImpl) run(name string, plan func(m *migrate.Migrate, version uint, hasVersion bool) ([]Step, error), apply func(m *migrate.Migrate) error) ([]Step, error) {
+	if mi == nil {
+		return nil, errors.New("migrate: migration is null")
+	}
+	m, err := mi.open()
+	if err != nil {
+		return nil, err
+	}
+	defer mi.close(m)
 
 	version, dirty, err := m.Version()
 	if err != nil && err != migrate.ErrNilVersion {
-		return errors.Wrap(err, "migrate : failed to get migration version")
+		return nil, errors.Wrap(err, "migrate : failed to get migration version")
 	}
+	hasVersion := err == nil
+	m.Log.Printf("migrate : run current schema version: %v dirty: %v", version, dirty)
 
 	if dirty {
-		// force to prior version to reattempt migration
-		err := m.Force(int(version) - 1)
-		if err != nil {
-			return errors.Wrap(err, "migrate : failed to force to previous previous schema version")
+		if version, hasVersion, err = mi.recover(m, version); err != nil {
+			return nil, err
 		}
-		m.Log.Printf("migrate : forced to previous version: %v to reattempt migration", int(version)-1)
 	}
 
-	m.Log.Printf("migrate : run current schema version: %v dirty: %v", version, dirty)
+	steps, err := plan(m, version, hasVersion)
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range steps {
+		m.Log.Printf("migrate : %v planned %v", name, step)
+	}
+	if mi.DryRun {
+		m.Log.Printf("migrate : dry run, %v migration(s) not applied", len(steps))
+		return steps, nil
+	}
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return errors.Wrap(err, "migrate: failed to invoke up migrate")
+	err = apply(m)
+	if _, ok := err.(migrate.ErrDirty); ok && mi.DirtyPolicy != DirtyFail {
+		// race condition if two or more front-ends reach db to run migration process.
+		version, _, verr := m.Version()
+		if verr != nil {
+			return nil, errors.Wrap(verr, "migrate : failed to reattempt migration migration version")
+		}
+		if _, _, err = mi.recover(m, version); err != nil {
+			return nil, err
+		}
+		err = apply(m)
+	}
+	if err == migrate.ErrLocked {
+		return nil, errors.Wrap(err, "migrate : failed to database locked")
+	}
+	if err != nil && err != migrate.ErrNoChange {
+		return nil, errors.Wrapf(err, "migrate: failed to invoke %v migrate", name)
 	}
 
-	if err != nil && err == migrate.ErrLocked {
-		return errors.Wrap(err, "migrate : failed to database locked")
+	m.Log.Printf("migrate: completed db migration")
+	return steps, nil
+}
+
+// recover forces the dirty version on the dirty policy, returning the forced version.
+// On dry run, the version is not forced.
+func (mi *migratorImpl) recover(m *migrate.Migrate, version uint) (uint, bool, error) {
+	forced, err := mi.recovery(version)
+	if err != nil {
+		return 0, false, err
+	}
+	if mi.DryRun {
+		m.Log.Printf("migrate : dry run, dirty version %v not forced to %v", version, forced)
+	} else {
+		if err := m.Force(forced); err != nil {
+			return 0, false, errors.Wrapf(err, "migrate : failed to force dirty version %v to %v", version, forced)
+		}
+		m.Log.Printf("migrate : forced dirty version %v to %v", version, forced)
+	}
+	if forced == database.NilVersion {
+		return 0, false, nil
 	}
+	return uint(forced), true, nil
+}
 
-	if _, ok := err.(migrate.ErrDirty); ok {
-		// race condition if two or more front-ends reach db to run migration process.
-		version, _, err := m.Version()
+// recovery returns the version the dirty version is forced to on the dirty policy: the prior version
+// to reattempt the failed migration, database.NilVersion when there is none, or the dirty version
+// itself to accept it as applied.
+func (mi *migratorImpl) recovery(version uint) (int, error) {
+	switch mi.DirtyPolicy {
+	case DirtyRetry:
+		prev, err := mi.source.Prev(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return database.NilVersion, nil
+		}
 		if err != nil {
-			return errors.Wrap(err, "migrate : failed to reattempt migration migration version")
+			return 0, errors.Wrapf(err, "migrate : failed to read the migration prior to %v", version)
 		}
+		return int(prev), nil
+	case DirtyAccept:
+		return int(version), nil
+	}
+	return 0, errors.Wrapf(migrate.ErrDirty{Version: int(version)}, "migrate : dirty schema version")
+}
 
-		mi.Logger.Printf("migrate : attempt to set recorded schema level to %d and rerun subsequent migration(s)", version)
-		err = m.Force(int(version) - 1)
-		if err != nil {
-			return errors.Wrap(err, "migrate : failed to force to previous schema version")
+// plan returns the steps from the current version to the target, nil target being the last migration.
+func (mi *migratorImpl) plan(version uint, hasVersion bool, target *uint) ([]Step, error) {
+	migrations, err := mi.migrations()
+	if err != nil {
+		return nil, err
+	}
+	var steps []Step
+	if target == nil || !hasVersion || *target > version {
+		for _, migration := range migrations {
+			if (!hasVersion || migration.Version > version) && (target == nil || migration.Version <= *target) {
+				steps = append(steps, Step{Migration: migration, Direction: source.Up})
+			}
 		}
+		return steps, nil
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if migrations[i].Version <= version && migrations[i].Version > *target {
+			steps = append(steps, Step{Migration: migrations[i], Direction: source.Down})
+		}
+	}
+	return steps, nil
+}
 
-		if err := m.Up(); err != nil {
-			return errors.Wrap(err, "migrate : re-migration attempt after force schema version")
+// migrations lists the source migrations in version order.
+func (mi *migratorImpl) migrations() ([]Migration, error) {
+	var migrations []Migration
+	version, err := mi.source.First()
+	for err == nil {
+		identifier, ierr := mi.identifier(version)
+		if ierr != nil {
+			return nil, ierr
 		}
+		migrations = append(migrations, Migration{Version: version, Identifier: identifier})
+		version, err = mi.source.Next(version)
 	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Wrap(err, "migrate : failed to read the source migrations")
+	}
+	return migrations, nil
+}
 
-	m.Log.Printf("migrate: completed db migration")
+func (mi *migratorImpl) identifier(version uint) (string, error) {
+	r, identifier, err := mi.source.ReadUp(version)
+	if err != nil {
+		return "", err
+	}
+	return identifier, r.Close()
+}
+
+func (mi *migratorImpl) open() (*migrate.Migrate, error) {
+	src, err := mi.openSource()
+	if err != nil {
+		return nil, errors.Wrapf(err, "migrate : failed to open source %v", mi.SourceURL)
+	}
 
-	_, err = m.Close()
-	return errors.Wrap(err, "migrate:  failed to close migrations connection")
+	connector, err := mi.Dialect.Connector(mi.DNS)
+	if err != nil {
+		_ = src.Close()
+		return nil, errors.Wrap(err, "db: failed to open connection")
+	}
+	session := sql.OpenDB(connector)
+
+	driver, err := mi.Dialect.MigrateDriver(session)
+	if err != nil {
+		_ = src.Close()
+		_ = session.Close()
+		return nil, err
+	}
+
+	m, err := migrate.NewWithInstance("source", src, mi.DBName, driver)
+	if err != nil {
+		_ = src.Close()
+		_ = driver.Close()
+		return nil, err
+	}
+
+	m.Log = mi.Logger
+	mi.SQLMigrate = m
+	mi.source = src
+	return m, nil
+}
+
+func (mi *migratorImpl) openSource() (source.Driver, error) {
+	if mi.SourceFS == nil {
+		return source.Open(mi.SourceURL)
+	}
+	path := mi.SourceURL
+	if path == "" {
+		path = "."
+	}
+	return iofs.New(mi.SourceFS, path)
+}
+
+func (mi *migratorImpl) close(m *migrate.Migrate) {
+	if srcErr, dbErr := m.Close(); srcErr != nil || dbErr != nil {
+		logx.Errorf("migrate:  failed to close migrations connection: %v, %v", srcErr, dbErr)
+	}
 }
 
 func (mi *migratorImpl) Printf(format string, v ...interface{}) {
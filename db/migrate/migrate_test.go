@@ -0,0 +1,235 @@
+package migrate_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mauricetjmurphy/ms-common/db/dialect/sqlite"
+	"github.com/mauricetjmurphy/ms-common/db/migrate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var migrations = map[string]string{
+	"1_create_dummy.up.sql":    "CREATE TABLE dummy (Id INTEGER PRIMARY KEY, Name TEXT);",
+	"1_create_dummy.down.sql":  "DROP TABLE dummy;",
+	"2_create_one_to.up.sql":   "CREATE TABLE one_to (Id INTEGER PRIMARY KEY, Status TEXT);",
+	"2_create_one_to.down.sql": "DROP TABLE one_to;",
+	"3_alter_missing.up.sql":   "ALTER TABLE missing ADD COLUMN Name TEXT;",
+	"3_alter_missing.down.sql": "SELECT 1;",
+}
+
+// newMigrator creates the migrator of a SQLite database on given source migrations.
+func newMigrator(t *testing.T, opts migrate.Options, files ...string) migrate.Migrator {
+	return newMigratorIn(t, t.TempDir(), opts, files...)
+}
+
+// newMigratorIn creates the migrator of the SQLite database in dir on given source migrations.
+func newMigratorIn(t *testing.T, dir string, opts migrate.Options, files ...string) migrate.Migrator {
+	for _, name := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(migrations[name]), 0o600))
+	}
+	opts.DSN = filepath.Join(dir, "test.db")
+	opts.DBName = "test"
+	opts.SourceURL = "file://" + dir
+	opts.Dialect = sqlite.Dialect()
+	mi, err := migrate.New(opts)
+	require.NoError(t, err)
+	return mi
+}
+
+var validFiles = []string{
+	"1_create_dummy.up.sql", "1_create_dummy.down.sql",
+	"2_create_one_to.up.sql", "2_create_one_to.down.sql",
+}
+
+func TestMigrator(t *testing.T) {
+	cases := []struct {
+		name        string
+		opts        migrate.Options
+		run         func(mi migrate.Migrator) error
+		wantVersion uint
+		wantApplied int
+		wantPending int
+	}{
+		{
+			name:        "Migrate_AppliesPending",
+			run:         func(mi migrate.Migrator) error { return mi.Migrate() },
+			wantVersion: 2,
+			wantApplied: 2,
+		},
+		{
+			name:        "Migrate_DryRun_NotApplied",
+			opts:        migrate.Options{DryRun: true},
+			run:         func(mi migrate.Migrator) error { return mi.Migrate() },
+			wantPending: 2,
+		},
+		{
+			name: "Down_RevertsLast",
+			run: func(mi migrate.Migrator) error {
+				if err := mi.Migrate(); err != nil {
+					return err
+				}
+				_, err := mi.Down(1)
+				return err
+			},
+			wantVersion: 1,
+			wantApplied: 1,
+			wantPending: 1,
+		},
+		{
+			name: "Goto_Version",
+			run: func(mi migrate.Migrator) error {
+				_, err := mi.Goto(1)
+				return err
+			},
+			wantVersion: 1,
+			wantApplied: 1,
+			wantPending: 1,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			mi := newMigrator(t, tt.opts, validFiles...)
+
+			//When
+			err := tt.run(mi)
+
+			//Then
+			require.NoError(t, err)
+			status, err := mi.Status()
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantVersion, status.Version)
+			assert.False(t, status.Dirty)
+			assert.Len(t, status.Applied, tt.wantApplied)
+			assert.Len(t, status.Pending, tt.wantPending)
+		})
+	}
+}
+
+func TestMigrator_Down_TooMany(t *testing.T) {
+	//Given
+	mi := newMigrator(t, migrate.Options{}, validFiles...)
+	_, err := mi.Goto(1)
+	require.NoError(t, err)
+
+	//When
+	_, err = mi.Down(2)
+
+	//Then
+	assert.Error(t, err)
+}
+
+func TestMigrator_DirtyPolicy(t *testing.T) {
+	cases := []struct {
+		name      string
+		policy    migrate.DirtyPolicy
+		wantErr   bool
+		wantDirty bool
+	}{
+		{
+			name:      "DirtyFail_Fails",
+			policy:    migrate.DirtyFail,
+			wantErr:   true,
+			wantDirty: true,
+		},
+		{
+			name:   "DirtyAccept_ForcesAsApplied",
+			policy: migrate.DirtyAccept,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			mi := newMigrator(t, migrate.Options{DirtyPolicy: tt.policy},
+				append(validFiles, "3_alter_missing.up.sql", "3_alter_missing.down.sql")...)
+			require.Error(t, mi.Migrate())
+
+			//When
+			err := mi.Migrate()
+
+			//Then
+			assert.Equal(t, tt.wantErr, err != nil)
+			status, err := mi.Status()
+			require.NoError(t, err)
+			assert.Equal(t, uint(3), status.Version)
+			assert.Equal(t, tt.wantDirty, status.Dirty)
+		})
+	}
+}
+
+// dirtyDir returns the directory of a SQLite database left dirty at version 3 by a failed migration.
+func dirtyDir(t *testing.T) string {
+	dir := t.TempDir()
+	mi := newMigratorIn(t, dir, migrate.Options{}, append(validFiles, "3_alter_missing.up.sql", "3_alter_missing.down.sql")...)
+	require.Error(t, mi.Migrate())
+	return dir
+}
+
+func TestMigrator_DirtyRetry(t *testing.T) {
+	//Given
+	dir := dirtyDir(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "3_alter_missing.up.sql"), []byte("ALTER TABLE dummy ADD COLUMN Title TEXT;"), 0o600))
+	mi := newMigratorIn(t, dir, migrate.Options{DirtyPolicy: migrate.DirtyRetry})
+
+	//When
+	steps, err := mi.Up()
+
+	//Then
+	require.NoError(t, err)
+	if assert.Len(t, steps, 1) {
+		assert.Equal(t, "3_alter_missing.up", steps[0].String())
+	}
+	status, err := mi.Status()
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), status.Version)
+	assert.False(t, status.Dirty)
+}
+
+func TestMigrator_DryRun_DirtySchema(t *testing.T) {
+	//Given
+	dir := dirtyDir(t)
+	mi := newMigratorIn(t, dir, migrate.Options{DirtyPolicy: migrate.DirtyRetry, DryRun: true})
+
+	//When
+	steps, err := mi.Up()
+
+	//Then
+	require.NoError(t, err)
+	if assert.Len(t, steps, 1) {
+		assert.Equal(t, "3_alter_missing.up", steps[0].String())
+	}
+	status, err := mi.Status()
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), status.Version)
+	assert.True(t, status.Dirty)
+}
+
+func TestMigrator_SourceFS(t *testing.T) {
+	//Given
+	fsys := fstest.MapFS{}
+	for _, name := range validFiles {
+		fsys["migrations/"+name] = &fstest.MapFile{Data: []byte(migrations[name])}
+	}
+	mi, err := migrate.New(migrate.Options{
+		DSN:       filepath.Join(t.TempDir(), "test.db"),
+		DBName:    "test",
+		SourceURL: "migrations",
+		SourceFS:  fsys,
+		Dialect:   sqlite.Dialect(),
+	})
+	require.NoError(t, err)
+
+	//When
+	err = mi.Migrate()
+
+	//Then
+	require.NoError(t, err)
+	status, err := mi.Status()
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), status.Version)
+	assert.Len(t, status.Applied, 2)
+}
@@ -2,13 +2,76 @@
 
 package migratesmocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	migrate "github.com/mauricetjmurphy/ms-common/db/migrate"
+	mock "github.com/stretchr/testify/mock"
+)
 
 // Migrator is an autogenerated mock type for the Migrator type
 type Migrator struct {
 	mock.Mock
 }
 
+// Down provides a mock function with given fields: n
+func (_m *Migrator) Down(n int) ([]migrate.Step, error) {
+	ret := _m.Called(n)
+
+	var r0 []migrate.Step
+	if rf, ok := ret.Get(0).(func(int) []migrate.Step); ok {
+		r0 = rf(n)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]migrate.Step)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(int) error); ok {
+		r1 = rf(n)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Force provides a mock function with given fields: version
+func (_m *Migrator) Force(version int) error {
+	ret := _m.Called(version)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int) error); ok {
+		r0 = rf(version)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Goto provides a mock function with given fields: version
+func (_m *Migrator) Goto(version uint) ([]migrate.Step, error) {
+	ret := _m.Called(version)
+
+	var r0 []migrate.Step
+	if rf, ok := ret.Get(0).(func(uint) []migrate.Step); ok {
+		r0 = rf(version)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]migrate.Step)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Migrate provides a mock function with given fields:
 func (_m *Migrator) Migrate() error {
 	ret := _m.Called()
@@ -22,3 +85,49 @@ func (_m *Migrator) Migrate() error {
 
 	return r0
 }
+
+// Status provides a mock function with given fields:
+func (_m *Migrator) Status() (*migrate.Status, error) {
+	ret := _m.Called()
+
+	var r0 *migrate.Status
+	if rf, ok := ret.Get(0).(func() *migrate.Status); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*migrate.Status)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Up provides a mock function with given fields:
+func (_m *Migrator) Up() ([]migrate.Step, error) {
+	ret := _m.Called()
+
+	var r0 []migrate.Step
+	if rf, ok := ret.Get(0).(func() []migrate.Step); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]migrate.Step)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,147 @@
+package migrate
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/pkg/errors"
+)
+
+const (
+	// TimestampLayout is the version layout of the timestamped migrations.
+	TimestampLayout = "20060102150405"
+
+	// maxSequentialVersion bounds the sequential versions, the greater versions are timestamps.
+	maxSequentialVersion = 1_000_000_000
+	// defaultSequentialDigits is the zero padded width of the first sequential version.
+	defaultSequentialDigits = 6
+)
+
+// Validate checks the migration files in the directory of fsys. Each version must have one up
+// and at most one down migration named <version>_<identifier>.(up|down).<ext>, and the
+// sequential versions must have no gap. The files looking like migrations, with an up or down
+// direction or a .sql extension, must follow the naming; the other files, e.g. README.md, are
+// ignored. All the problems found are reported in the error.
+func Validate(fsys fs.FS, dir string) error {
+	migrations, invalid, err := readMigrations(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, name := range invalid {
+		problems = append(problems, fmt.Sprintf("file %v is not named <version>_<identifier>.(up|down).<ext>", name))
+	}
+	versions := make([]uint, 0, len(migrations))
+	for version, files := range migrations {
+		versions = append(versions, version)
+		if ups := files[source.Up]; len(ups) == 0 {
+			problems = append(problems, fmt.Sprintf("version %v has no up migration", version))
+		} else if len(ups) > 1 {
+			problems = append(problems, fmt.Sprintf("version %v has duplicate up migrations %v", version, strings.Join(ups, ", ")))
+		}
+		if downs := files[source.Down]; len(downs) > 1 {
+			problems = append(problems, fmt.Sprintf("version %v has duplicate down migrations %v", version, strings.Join(downs, ", ")))
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	for i := 1; i < len(versions); i++ {
+		if versions[i] < maxSequentialVersion && versions[i] != versions[i-1]+1 {
+			problems = append(problems, fmt.Sprintf("versions %v to %v are missing", versions[i-1]+1, versions[i]-1))
+		}
+	}
+	sort.Strings(problems)
+
+	if len(problems) > 0 {
+		return errors.Errorf("migrate : invalid migrations in %v: %v", dir, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Create writes the empty up and down SQL files of a new migration in dir and returns their paths.
+// The version follows the last sequential version when seq is set, or is the timestamp of now.
+func Create(dir, name string, seq bool, now time.Time) (up, down string, err error) {
+	identifier := strings.ToLower(strings.Join(strings.Fields(name), "_"))
+	if identifier == "" {
+		return "", "", errors.New("migrate : missing migration name")
+	}
+	migrations, _, err := readMigrations(os.DirFS(dir), ".")
+	if err != nil {
+		return "", "", err
+	}
+
+	version := now.UTC().Format(TimestampLayout)
+	if seq {
+		var last uint
+		digits := defaultSequentialDigits
+		for v, files := range migrations {
+			if v >= maxSequentialVersion {
+				return "", "", errors.Errorf("migrate : cannot create sequential version after timestamp version %v", v)
+			}
+			if v > last {
+				last = v
+				for _, file := range append(files[source.Up], files[source.Down]...) {
+					digits = strings.Index(file, "_")
+				}
+			}
+		}
+		version = fmt.Sprintf("%0*d", digits, last+1)
+	}
+
+	up = filepath.Join(dir, fmt.Sprintf("%v_%v.%v.sql", version, identifier, source.Up))
+	down = filepath.Join(dir, fmt.Sprintf("%v_%v.%v.sql", version, identifier, source.Down))
+	for _, file := range []string{up, down} {
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return "", "", errors.Wrapf(err, "migrate : failed to create migration %v", file)
+		}
+		if err := f.Close(); err != nil {
+			return "", "", errors.Wrapf(err, "migrate : failed to create migration %v", file)
+		}
+	}
+	return up, down, nil
+}
+
+// readMigrations lists the migration file names of the directory by version and direction, and the
+// names of the files looking like migrations which failed to parse.
+func readMigrations(fsys fs.FS, dir string) (map[uint]map[source.Direction][]string, []string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "migrate : failed to read migrations in %v", dir)
+	}
+	migrations := make(map[uint]map[source.Direction][]string)
+	var invalid []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := path.Base(entry.Name())
+		m, err := source.Parse(name)
+		if err != nil {
+			if isMigrationLike(name) {
+				invalid = append(invalid, name)
+			}
+			continue
+		}
+		if migrations[m.Version] == nil {
+			migrations[m.Version] = make(map[source.Direction][]string)
+		}
+		migrations[m.Version][m.Direction] = append(migrations[m.Version][m.Direction], m.Raw)
+	}
+	return migrations, invalid, nil
+}
+
+// isMigrationLike reports whether the file name looks like a migration, having an up or down
+// direction or a .sql extension.
+func isMigrationLike(name string) bool {
+	return strings.Contains(name, "."+string(source.Up)+".") ||
+		strings.Contains(name, "."+string(source.Down)+".") ||
+		strings.EqualFold(path.Ext(name), ".sql")
+}
@@ -0,0 +1,121 @@
+package migrate_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/mauricetjmurphy/ms-common/db/migrate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		files   []string
+		wantErr string
+	}{
+		{
+			name:  "Validate_Sequential",
+			files: []string{"1_a.up.sql", "1_a.down.sql", "2_b.up.sql", "README.md"},
+		},
+		{
+			name:  "Validate_Timestamps",
+			files: []string{"20240101000000_a.up.sql", "20240301000000_b.up.sql"},
+		},
+		{
+			name:    "Validate_DuplicateVersion",
+			files:   []string{"1_a.up.sql", "1_b.up.sql"},
+			wantErr: "version 1 has duplicate up migrations 1_a.up.sql, 1_b.up.sql",
+		},
+		{
+			name:    "Validate_Gap",
+			files:   []string{"1_a.up.sql", "4_b.up.sql"},
+			wantErr: "versions 2 to 3 are missing",
+		},
+		{
+			name:    "Validate_UnparseableName",
+			files:   []string{"1_a.up.sql", "2-b.up.sql", "b.down.sql", "notes.sql"},
+			wantErr: "file 2-b.up.sql is not named <version>_<identifier>.(up|down).<ext>; file b.down.sql is not named",
+		},
+		{
+			name:    "Validate_MissingUp",
+			files:   []string{"1_a.up.sql", "2_b.down.sql"},
+			wantErr: "version 2 has no up migration",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			fsys := fstest.MapFS{}
+			for _, name := range tt.files {
+				fsys["migrations/"+name] = &fstest.MapFile{}
+			}
+
+			//When
+			err := migrate.Validate(fsys, "migrations")
+
+			//Then
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		existing []string
+		seq      bool
+		wantUp   string
+		wantDown string
+	}{
+		{
+			name:     "Create_Timestamp",
+			wantUp:   "20240102030405_add_title.up.sql",
+			wantDown: "20240102030405_add_title.down.sql",
+		},
+		{
+			name:     "Create_Sequential_First",
+			seq:      true,
+			wantUp:   "000001_add_title.up.sql",
+			wantDown: "000001_add_title.down.sql",
+		},
+		{
+			name:     "Create_Sequential_Next",
+			existing: []string{"0001_a.up.sql", "0002_b.up.sql"},
+			seq:      true,
+			wantUp:   "0003_add_title.up.sql",
+			wantDown: "0003_add_title.down.sql",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			dir := t.TempDir()
+			for _, name := range tt.existing {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o600))
+			}
+
+			//When
+			up, down, err := migrate.Create(dir, "Add Title", tt.seq, now)
+
+			//Then
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(dir, tt.wantUp), up)
+			assert.Equal(t, filepath.Join(dir, tt.wantDown), down)
+			assert.FileExists(t, up)
+			assert.FileExists(t, down)
+		})
+	}
+}
@@ -1,5 +1,14 @@
 package db
 
+import (
+	"io/fs"
+	"time"
+
+	"github.com/mauricetjmurphy/ms-common/db/dialect"
+	"github.com/mauricetjmurphy/ms-common/db/migrate"
+	"gorm.io/gorm/logger"
+)
+
 // Option is a database configuration option.
 type Option func(*Config)
 
@@ -58,3 +67,176 @@ func MigrationSourceURL(sourceFileURL string) Option {
 		c.MigrationSourceURL = sourceFileURL
 	}
 }
+
+// MigrationFS sets the file system holding the migration scripts, e.g. an embed.FS compiled into the binary,
+// and the directory of the scripts in it.
+func MigrationFS(fsys fs.FS, dir string) Option {
+	return func(c *Config) {
+		c.MigrationFS = fsys
+		c.MigrationSourceURL = dir
+	}
+}
+
+// MigrationDirtyPolicy sets the recovery of a dirty schema version left by a failed migration.
+func MigrationDirtyPolicy(policy migrate.DirtyPolicy) Option {
+	return func(c *Config) {
+		c.MigrationDirtyPolicy = policy
+	}
+}
+
+// MigrationDryRun sets the migrations to be logged without being applied.
+func MigrationDryRun(dryRun bool) Option {
+	return func(c *Config) {
+		c.MigrationDryRun = dryRun
+	}
+}
+
+// ReaderHosts sets the read replica hostnames. The replicas share the port, name and credentials of the primary.
+func ReaderHosts(hosts ...string) Option {
+	return func(c *Config) {
+		c.ReaderHosts = append(c.ReaderHosts, hosts...)
+	}
+}
+
+// ReaderAWSSecretID sets the name of the AWS secret ID holding the read replica connection to operate on.
+func ReaderAWSSecretID(secretID string) Option {
+	return func(c *Config) {
+		c.ReaderAWSSecretID = secretID
+	}
+}
+
+// MaxOpenConns sets the maximum number of open connections of each connection pool.
+func MaxOpenConns(n int) Option {
+	return func(c *Config) {
+		c.MaxOpenConns = n
+	}
+}
+
+// MaxIdleConns sets the maximum number of idle connections of each connection pool.
+func MaxIdleConns(n int) Option {
+	return func(c *Config) {
+		c.MaxIdleConns = n
+	}
+}
+
+// ConnMaxLifetime sets the maximum amount of time a connection may be reused.
+func ConnMaxLifetime(d time.Duration) Option {
+	return func(c *Config) {
+		c.ConnMaxLifetime = d
+	}
+}
+
+// ConnMaxIdleTime sets the maximum amount of time a connection may be idle.
+func ConnMaxIdleTime(d time.Duration) Option {
+	return func(c *Config) {
+		c.ConnMaxIdleTime = d
+	}
+}
+
+// Location sets the location used to parse and format the time.Time values.
+func Location(loc *time.Location) Option {
+	return func(c *Config) {
+		c.Loc = loc
+	}
+}
+
+// TLS sets the TLS mode of the connections: true, false, skip-verify, preferred or a config name
+// registered with mysql.RegisterTLSConfig.
+func TLS(mode string) Option {
+	return func(c *Config) {
+		c.TLS = mode
+	}
+}
+
+// DialTimeout sets the timeout for establishing new connections.
+func DialTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.DialTimeout = d
+	}
+}
+
+// ReadTimeout sets the I/O read timeout of the connections.
+func ReadTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.ReadTimeout = d
+	}
+}
+
+// WriteTimeout sets the I/O write timeout of the connections.
+func WriteTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.WriteTimeout = d
+	}
+}
+
+// Param sets an extra DSN parameter, e.g. Param("time_zone", "'+00:00'") to set the session time zone.
+func Param(name, value string) Option {
+	return func(c *Config) {
+		if c.Params == nil {
+			c.Params = make(map[string]string)
+		}
+		c.Params[name] = value
+	}
+}
+
+// BatchSize sets the number of rows written per statement by CreateInBatches and Upsert.
+func BatchSize(size int) Option {
+	return func(c *Config) {
+		c.BatchSize = size
+	}
+}
+
+// LogLevel sets the statements logging level: logger.Info logs every statement at debug level,
+// logger.Warn the slow and the failed ones, logger.Error the failed ones and logger.Silent none.
+func LogLevel(level logger.LogLevel) Option {
+	return func(c *Config) {
+		c.LogLevel = level
+	}
+}
+
+// SlowThreshold sets the duration above which a statement is logged as slow, zero disables it.
+func SlowThreshold(d time.Duration) Option {
+	return func(c *Config) {
+		c.SlowThreshold = d
+	}
+}
+
+// LogParams logs the bound parameters of the statements, which are redacted by default.
+func LogParams(enabled bool) Option {
+	return func(c *Config) {
+		c.LogParams = enabled
+	}
+}
+
+// Metrics sets the sink receiving the metrics of every executed statement.
+func Metrics(sink MetricsSink) Option {
+	return func(c *Config) {
+		c.Metrics = sink
+	}
+}
+
+// WaitForDB sets how long New waits, with backoff, for the database to be reachable before failing,
+// e.g. while the database container of the local environment is starting.
+func WaitForDB(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.WaitTimeout = timeout
+	}
+}
+
+// SecretRefreshInterval sets the interval to check the AWS secret for a rotated version, zero disables the check.
+// The credentials are re-fetched on authentication failures regardless of the interval. Only the new
+// connections use the rotated credentials and retry on an authentication failure: the connections already
+// open keep the credentials they were opened with until the pool closes them.
+func SecretRefreshInterval(d time.Duration) Option {
+	return func(c *Config) {
+		c.SecretRefreshInterval = d
+	}
+}
+
+// Dialect sets the SQL database to connect to, e.g. postgres.Dialect() or sqlite.Dialect().
+// The port defaults to the dialect default port when not set.
+func Dialect(d dialect.Dialect) Option {
+	return func(c *Config) {
+		c.Dialect = d
+	}
+}
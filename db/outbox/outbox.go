@@ -0,0 +1,105 @@
+// Package outbox provides the transactional outbox of the EventBridge messages.
+//
+// The messages are written with Write, or with the Publisher of a transaction, in the same
+// transaction as the entity changes they describe, and are published once committed by the Relay.
+// The outbox table is created by the service migrations, e.g. on MySQL:
+//
+//	CREATE TABLE OutboxEvent (
+//	    Id               BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+//	    AggregateKey     VARCHAR(255) NOT NULL,
+//	    EventSource      VARCHAR(255) NOT NULL,
+//	    EventDetailType  VARCHAR(255) NOT NULL,
+//	    Detail           LONGTEXT     NOT NULL,
+//	    Attempts         INT UNSIGNED NOT NULL DEFAULT 0,
+//	    NextAttemptDatim DATETIME(3)  NOT NULL,
+//	    LastError        TEXT,
+//	    SentDatim        DATETIME(3),
+//	    FailedDatim      DATETIME(3),
+//	    CreatedDatim     DATETIME(3)  NOT NULL,
+//	    INDEX idx_OutboxEvent_pending (SentDatim, FailedDatim, Id)
+//	);
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/mauricetjmurphy/ms-common/clients/aws/eventbridge"
+	"github.com/mauricetjmurphy/ms-common/db"
+	"github.com/mauricetjmurphy/ms-common/db/entity"
+	"github.com/pkg/errors"
+)
+
+// Event is one message of the outbox table.
+type Event struct {
+	*entity.Base
+	// AggregateKey orders the messages, the messages of one key are published in writing order.
+	AggregateKey    string `gorm:"column:AggregateKey;size:255;not null"`
+	EventSource     string `gorm:"column:EventSource;size:255;not null"`
+	EventDetailType string `gorm:"column:EventDetailType;size:255;not null"`
+	// Detail is the JSON encoded eventbridge.DetailEvent.
+	Detail string `gorm:"column:Detail;not null"`
+	// Attempts is the number of failed publish attempts.
+	Attempts         uint       `gorm:"column:Attempts;not null;default:0"`
+	NextAttemptDatim time.Time  `gorm:"column:NextAttemptDatim;not null"`
+	LastError        string     `gorm:"column:LastError"`
+	SentDatim        *time.Time `gorm:"column:SentDatim;index:idx_OutboxEvent_pending,priority:1"`
+	// FailedDatim is set when the message is given up after the relay max attempts.
+	FailedDatim  *time.Time `gorm:"column:FailedDatim;index:idx_OutboxEvent_pending,priority:2"`
+	CreatedDatim time.Time  `gorm:"column:CreatedDatim;autoCreateTime"`
+}
+
+// TableName returns the outbox table name.
+func (Event) TableName() string { return "OutboxEvent" }
+
+// Write stores the message in the outbox on given transaction, the message is published
+// by the Relay once the transaction is committed.
+func Write(ctx context.Context, tx db.DB, aggregateKey string, message *eventbridge.Message) error {
+	detail, err := json.Marshal(message.Detail)
+	if err != nil {
+		return errors.Wrap(err, "outbox : failed to encode the message detail")
+	}
+	event := &Event{
+		AggregateKey:     aggregateKey,
+		EventSource:      message.EventSource,
+		EventDetailType:  message.EventDetailType,
+		Detail:           string(detail),
+		NextAttemptDatim: time.Now(),
+	}
+	if err := tx.DBInstance().WithContext(ctx).Create(event).Error; err != nil {
+		return errors.Wrap(err, "outbox : failed to write the message")
+	}
+	return nil
+}
+
+// Publisher returns the publisher writing the messages in the outbox on given transaction,
+// in place of the EventBridge publisher, with given aggregate key.
+func Publisher(tx db.DB, aggregateKey string) eventbridge.PublisherFunc {
+	return func(ctx context.Context, message *eventbridge.Message) error {
+		return Write(ctx, tx, aggregateKey, message)
+	}
+}
+
+// message decodes the event into the message to be published, the payload is kept encoded.
+func (e *Event) message() (*eventbridge.Message, error) {
+	message := &eventbridge.Message{
+		EventSource:     e.EventSource,
+		EventDetailType: e.EventDetailType,
+	}
+	if e.Detail == "null" {
+		return message, nil
+	}
+	var detail struct {
+		EventID string          `json:"eventId"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal([]byte(e.Detail), &detail); err != nil {
+		return nil, errors.Wrap(err, "outbox : failed to decode the message detail")
+	}
+	message.Detail = &eventbridge.DetailEvent{EventID: detail.EventID}
+	if len(detail.Payload) > 0 {
+		message.Detail.Payload = detail.Payload
+	}
+	return message, nil
+}
@@ -0,0 +1,247 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mauricetjmurphy/ms-common/clients/aws/eventbridge"
+	"github.com/mauricetjmurphy/ms-common/db"
+	"github.com/mauricetjmurphy/ms-common/db/dialect/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newDB(t *testing.T) db.DB {
+	conn, err := db.New(context.Background(),
+		db.Dialect(sqlite.Dialect()),
+		db.Name(sqlite.Memory),
+		db.MaxOpenConns(1),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	require.NoError(t, conn.DBInstance().AutoMigrate(&Event{}))
+	return conn
+}
+
+func message(eventID string) *eventbridge.Message {
+	return &eventbridge.Message{
+		EventSource:     "rights",
+		EventDetailType: "RightsUpdated",
+		Detail:          &eventbridge.DetailEvent{EventID: eventID, Payload: map[string]string{"id": eventID}},
+	}
+}
+
+// recorder publishes the messages, failing on the failing event IDs.
+type recorder struct {
+	published []string
+	failing   map[string]bool
+}
+
+func (r *recorder) PublishEvent(_ context.Context, message *eventbridge.Message) error {
+	if r.failing[message.Detail.EventID] {
+		return errors.New("eventbridge unavailable")
+	}
+	r.published = append(r.published, message.Detail.EventID)
+	return nil
+}
+
+func TestWrite_Transaction(t *testing.T) {
+	//Given
+	conn := newDB(t)
+	errRollback := errors.New("rollback")
+
+	//When
+	require.NoError(t, conn.Transaction(context.Background(), func(tx db.DB) error {
+		return Publisher(tx, "1").PublishEvent(context.Background(), message("committed"))
+	}))
+	err := conn.Transaction(context.Background(), func(tx db.DB) error {
+		if err := Write(context.Background(), tx, "1", message("rolled-back")); err != nil {
+			return err
+		}
+		return errRollback
+	})
+
+	//Then
+	assert.Equal(t, errRollback, err)
+	var events []*Event
+	require.NoError(t, conn.Query().Find(context.Background(), &events))
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "1", events[0].AggregateKey)
+		assert.Equal(t, "rights", events[0].EventSource)
+		assert.Equal(t, "RightsUpdated", events[0].EventDetailType)
+		assert.JSONEq(t, `{"eventId":"committed","payload":{"id":"committed"}}`, events[0].Detail)
+	}
+}
+
+func TestRelay_Process(t *testing.T) {
+	cases := []struct {
+		name          string
+		opts          []Option
+		failing       []string
+		wantPublished []string
+		wantPending   []string
+		wantFailed    []string
+		wantSent      int
+	}{
+		{
+			name:          "Process_PublishedInOrder",
+			wantPublished: []string{"a1", "b1", "a2"},
+			wantSent:      3,
+		},
+		{
+			name:          "Process_Failed_HoldsBackAggregateKey",
+			failing:       []string{"a1"},
+			wantPublished: []string{"b1"},
+			wantPending:   []string{"a1", "a2"},
+			wantSent:      1,
+		},
+		{
+			name:          "Process_MaxAttempts_GivenUp",
+			opts:          []Option{MaxAttempts(1)},
+			failing:       []string{"a1"},
+			wantPublished: []string{"b1", "a2"},
+			wantFailed:    []string{"a1"},
+			wantSent:      2,
+		},
+		{
+			name:          "Process_NoRetention_DeletesSent",
+			opts:          []Option{Retention(0)},
+			wantPublished: []string{"a1", "b1", "a2"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			conn := newDB(t)
+			require.NoError(t, conn.Transaction(context.Background(), func(tx db.DB) error {
+				for _, e := range []struct{ key, id string }{{"a", "a1"}, {"b", "b1"}, {"a", "a2"}} {
+					if err := Write(context.Background(), tx, e.key, message(e.id)); err != nil {
+						return err
+					}
+				}
+				return nil
+			}))
+			failing := make(map[string]bool)
+			for _, id := range tt.failing {
+				failing[id] = true
+			}
+			publisher := &recorder{failing: failing}
+			relay := NewRelay(conn, publisher, tt.opts...)
+
+			//When
+			published, err := relay.Process(context.Background())
+
+			//Then
+			require.NoError(t, err)
+			assert.Equal(t, len(tt.wantPublished), published)
+			assert.Equal(t, tt.wantPublished, publisher.published)
+			var events []*Event
+			require.NoError(t, conn.Query().Order("Id").Find(context.Background(), &events))
+			var pending, failed []string
+			var sent int
+			for _, e := range events {
+				m, err := e.message()
+				require.NoError(t, err)
+				switch {
+				case e.SentDatim != nil:
+					sent++
+				case e.FailedDatim != nil:
+					failed = append(failed, m.Detail.EventID)
+				default:
+					pending = append(pending, m.Detail.EventID)
+				}
+				if failing[m.Detail.EventID] {
+					assert.Equal(t, uint(1), e.Attempts)
+					assert.Equal(t, "eventbridge unavailable", e.LastError)
+				}
+			}
+			assert.Equal(t, tt.wantPending, pending)
+			assert.Equal(t, tt.wantFailed, failed)
+			assert.Equal(t, tt.wantSent, sent)
+		})
+	}
+}
+
+func TestRelay_Process_Retry(t *testing.T) {
+	//Given
+	conn := newDB(t)
+	require.NoError(t, Write(context.Background(), conn, "a", message("a1")))
+	publisher := &recorder{failing: map[string]bool{"a1": true}}
+	relay := NewRelay(conn, publisher)
+	_, err := relay.Process(context.Background())
+	require.NoError(t, err)
+
+	delete(publisher.failing, "a1")
+	_, err = relay.Process(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, publisher.published, "published before its next attempt")
+
+	//When
+	relay.now = func() time.Time { return time.Now().Add(DefaultMinBackoff) }
+	_, err = relay.Process(context.Background())
+
+	//Then
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1"}, publisher.published)
+}
+
+func TestRelay_Process_BackingOffKey_DoesNotStarve(t *testing.T) {
+	//Given
+	conn := newDB(t)
+	for _, e := range []struct{ key, id string }{{"a", "a1"}, {"a", "a2"}, {"a", "a3"}, {"b", "b1"}} {
+		require.NoError(t, Write(context.Background(), conn, e.key, message(e.id)))
+	}
+	publisher := &recorder{failing: map[string]bool{"a1": true}}
+	relay := NewRelay(conn, publisher, BatchSize(2))
+	_, err := relay.Process(context.Background())
+	require.NoError(t, err)
+
+	//When
+	published, err := relay.Process(context.Background())
+
+	//Then
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, []string{"b1"}, publisher.published)
+}
+
+func TestRelay_Run_HeldBackBatch(t *testing.T) {
+	//Given
+	conn := newDB(t)
+	require.NoError(t, Write(context.Background(), conn, "a", message("a1")))
+	var reads int
+	require.NoError(t, conn.DBInstance().Callback().Query().After("gorm:query").
+		Register("test:reads", func(tx *gorm.DB) {
+			if !tx.DryRun {
+				reads++
+			}
+		}))
+	publisher := &recorder{failing: map[string]bool{"a1": true}}
+	relay := NewRelay(conn, publisher, BatchSize(1), Interval(time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	//When
+	err := relay.Run(ctx)
+
+	//Then
+	assert.NoError(t, err)
+	assert.Equal(t, 1, reads, "full batch in backoff read again without waiting")
+}
+
+func TestRelay_Backoff(t *testing.T) {
+	//Given
+	relay := NewRelay(nil, nil, Backoff(time.Second, 5*time.Second))
+
+	//When
+	var delays []time.Duration
+	for attempts := uint(1); attempts <= 4; attempts++ {
+		delays = append(delays, relay.backoff(attempts))
+	}
+
+	//Then
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}, delays)
+}
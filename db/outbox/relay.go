@@ -0,0 +1,245 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/mauricetjmurphy/ms-common/clients/aws/eventbridge"
+	"github.com/mauricetjmurphy/ms-common/db"
+	"github.com/mauricetjmurphy/ms-common/db/query/criteria"
+	"github.com/mauricetjmurphy/ms-common/logx"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DefaultInterval    = time.Second
+	DefaultBatchSize   = 100
+	DefaultMinBackoff  = time.Second
+	DefaultMaxBackoff  = 5 * time.Minute
+	DefaultMaxAttempts = 10
+	DefaultRetention   = 24 * time.Hour
+)
+
+// Config presents the relay configuration.
+type Config struct {
+	// Interval is the polling interval of the outbox table.
+	Interval time.Duration
+	// BatchSize is the number of messages read per poll.
+	BatchSize int
+	// MinBackoff and MaxBackoff bound the exponential delay before retrying a failed message.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxAttempts is the number of attempts before a message is given up, zero retries forever.
+	// A given up message no longer holds back the next messages of its aggregate key.
+	MaxAttempts uint
+	// Retention is how long the sent messages are kept, zero deletes them once published.
+	Retention time.Duration
+}
+
+// Option is a relay configuration option.
+type Option func(*Config)
+
+// Interval provides the polling interval of the outbox table.
+func Interval(d time.Duration) Option {
+	return func(c *Config) {
+		c.Interval = d
+	}
+}
+
+// BatchSize provides the number of messages read per poll.
+func BatchSize(n int) Option {
+	return func(c *Config) {
+		c.BatchSize = n
+	}
+}
+
+// Backoff provides the bounds of the delay before retrying a failed message.
+func Backoff(min, max time.Duration) Option {
+	return func(c *Config) {
+		c.MinBackoff, c.MaxBackoff = min, max
+	}
+}
+
+// MaxAttempts provides the number of attempts before a message is given up, zero retries forever.
+func MaxAttempts(n uint) Option {
+	return func(c *Config) {
+		c.MaxAttempts = n
+	}
+}
+
+// Retention provides how long the sent messages are kept, zero deletes them once published.
+func Retention(d time.Duration) Option {
+	return func(c *Config) {
+		c.Retention = d
+	}
+}
+
+// Relay publishes the outbox messages. The messages of one aggregate key are published in writing
+// order, a failed message holding back the next ones of its key until it is sent or given up.
+// One relay should run per outbox table, e.g. guarded by a distributed lock, to keep the order.
+type Relay struct {
+	db        db.DB
+	publisher eventbridge.Publisher
+	cfg       *Config
+	now       func() time.Time
+}
+
+// NewRelay creates the relay publishing the outbox messages of given DB with the publisher,
+// e.g. the EventBridge publisher.New.
+func NewRelay(conn db.DB, publisher eventbridge.Publisher, opts ...Option) *Relay {
+	cfg := &Config{
+		Interval:    DefaultInterval,
+		BatchSize:   DefaultBatchSize,
+		MinBackoff:  DefaultMinBackoff,
+		MaxBackoff:  DefaultMaxBackoff,
+		MaxAttempts: DefaultMaxAttempts,
+		Retention:   DefaultRetention,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return &Relay{db: conn, publisher: publisher, cfg: cfg, now: time.Now}
+}
+
+// Run publishes the outbox messages on every interval until the context is done.
+// A batch publishing messages is followed by the next one without waiting, the messages held back
+// or waiting for their next attempt being left to the next interval.
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		published, err := r.Process(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			logx.Errorf("outbox : failed to relay the messages: %v", err)
+		}
+		if err == nil && published > 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Process publishes one batch of the pending messages and deletes the sent messages past
+// the retention. It returns the number of messages published. The messages are read from the
+// primary, a replica lagging behind would publish the sent messages again. The batch only holds
+// the messages due for an attempt and not held back by an earlier message of their key waiting
+// for its next attempt, the messages of a key backing off not to starve the other keys.
+func (r *Relay) Process(ctx context.Context) (int, error) {
+	now := r.now()
+	tx := r.db.DBInstance().WithContext(db.ForcePrimary(ctx))
+	table := Event{}.TableName()
+	waiting := tx.Session(&gorm.Session{NewDB: true}).
+		Table("?", clause.Table{Name: table, Alias: "waiting"}).
+		Select("1").
+		Where(criteria.Eq("waiting.AggregateKey", clause.Column{Table: table, Name: "AggregateKey"})).
+		Where(criteria.Lt("waiting.Id", clause.Column{Table: table, Name: "Id"})).
+		Where(criteria.IsNil("waiting.SentDatim")).
+		Where(criteria.IsNil("waiting.FailedDatim")).
+		Where(criteria.Gt("waiting.NextAttemptDatim", now))
+	var events []*Event
+	err := tx.
+		Where(criteria.IsNil("SentDatim")).
+		Where(criteria.IsNil("FailedDatim")).
+		Where(criteria.Lte("NextAttemptDatim", now)).
+		Where(criteria.NotExists(waiting)).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "Id"}}).
+		Limit(r.cfg.BatchSize).
+		Find(&events).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "outbox : failed to read the pending messages")
+	}
+
+	var published int
+	held := make(map[string]bool)
+	for _, event := range events {
+		if held[event.AggregateKey] {
+			continue
+		}
+		if err := r.publish(ctx, event); err != nil {
+			if ctx.Err() != nil {
+				return published, ctx.Err()
+			}
+			held[event.AggregateKey] = !r.retry(ctx, event, err)
+			continue
+		}
+		published++
+		if err := r.sent(ctx, event); err != nil {
+			return published, err
+		}
+	}
+	return published, r.cleanup(ctx)
+}
+
+func (r *Relay) publish(ctx context.Context, event *Event) error {
+	message, err := event.message()
+	if err != nil {
+		return err
+	}
+	return r.publisher.PublishEvent(ctx, message)
+}
+
+// sent marks the message sent, or deletes it when the sent messages are not retained.
+func (r *Relay) sent(ctx context.Context, event *Event) error {
+	tx := r.db.DBInstance().WithContext(ctx)
+	if r.cfg.Retention <= 0 {
+		err := tx.Delete(event).Error
+		return errors.Wrapf(err, "outbox : failed to delete the sent message %v", event.ID)
+	}
+	err := tx.Model(event).Update("SentDatim", r.now()).Error
+	return errors.Wrapf(err, "outbox : failed to mark the message %v sent", event.ID)
+}
+
+// retry schedules the next attempt of the failed message, or gives it up after the max attempts.
+// It returns whether the message is given up.
+func (r *Relay) retry(ctx context.Context, event *Event, cause error) bool {
+	attempts := event.Attempts + 1
+	values := map[string]interface{}{
+		"Attempts":  attempts,
+		"LastError": cause.Error(),
+	}
+	givenUp := r.cfg.MaxAttempts > 0 && attempts >= r.cfg.MaxAttempts
+	if givenUp {
+		values["FailedDatim"] = r.now()
+		logx.Errorf("outbox : message %v of %v given up after %v attempts: %v", event.ID, event.AggregateKey, attempts, cause)
+	} else {
+		values["NextAttemptDatim"] = r.now().Add(r.backoff(attempts))
+		logx.Warnf("outbox : failed to publish the message %v of %v, attempt %v: %v", event.ID, event.AggregateKey, attempts, cause)
+	}
+	if err := r.db.DBInstance().WithContext(ctx).Model(event).Updates(values).Error; err != nil {
+		logx.Errorf("outbox : failed to reschedule the message %v: %v", event.ID, err)
+		return false
+	}
+	return givenUp
+}
+
+// backoff returns the delay before the next attempt, doubling on every failed attempt.
+func (r *Relay) backoff(attempts uint) time.Duration {
+	delay := r.cfg.MinBackoff
+	for i := uint(1); i < attempts && delay < r.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.cfg.MaxBackoff {
+		return r.cfg.MaxBackoff
+	}
+	return delay
+}
+
+// cleanup deletes the sent messages past the retention.
+func (r *Relay) cleanup(ctx context.Context) error {
+	if r.cfg.Retention <= 0 {
+		return nil
+	}
+	err := r.db.DBInstance().WithContext(ctx).
+		Where(criteria.Lt("SentDatim", r.now().Add(-r.cfg.Retention))).
+		Delete(&Event{}).Error
+	return errors.Wrap(err, "outbox : failed to delete the sent messages")
+}
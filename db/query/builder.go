@@ -33,7 +33,7 @@ func (q *Query) SelectCount(columns ...string) *Query {
 
 // From provides the source table name to be selected and possibly other clauses.
 func (q *Query) From(table, alias string) *Query {
-	q.from = table
+	q.from, q.alias = table, alias
 	if len(alias) > 0 {
 		q.from = fmt.Sprintf("%v AS %v", q.from, alias)
 	}
@@ -116,6 +116,18 @@ func (q *Query) Page(offset, limit int64) *Query {
 	return q
 }
 
+// WithDeleted includes the soft deleted rows in the result set.
+func (q *Query) WithDeleted() *Query {
+	q.withDeleted = true
+	return q
+}
+
+// OnlyDeleted returns only the soft deleted rows in the result set.
+func (q *Query) OnlyDeleted() *Query {
+	q.onlyDeleted = true
+	return q
+}
+
 // Preloads loads relation entity models to be allowed eager reloading.
 // See https://gorm.io/docs/preload.html for more usage.
 func (q *Query) Preloads(models ...string) *Query {
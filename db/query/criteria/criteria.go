@@ -1,6 +1,7 @@
 package criteria
 
 import (
+	"reflect"
 	"time"
 
 	"gorm.io/gorm/clause"
@@ -63,6 +64,132 @@ func EqUintIfPresent(column string, value uint) Expr {
 	return nil
 }
 
+// EqIfPresent clauses the equal expression unless the value is nil or a nil pointer.
+func EqIfPresent(column string, value interface{}) Expr {
+	if v, ok := present(value); ok {
+		return Eq(column, v)
+	}
+	return nil
+}
+
+// Gt clauses the greater than expression. Ex: column > value
+func Gt(column string, value interface{}) Expr {
+	return clause.Gt{Column: column, Value: value}
+}
+
+// Gte clauses the greater than or equal expression. Ex: column >= value
+func Gte(column string, value interface{}) Expr {
+	return clause.Gte{Column: column, Value: value}
+}
+
+// Lt clauses the less than expression. Ex: column < value
+func Lt(column string, value interface{}) Expr {
+	return clause.Lt{Column: column, Value: value}
+}
+
+// Lte clauses the less than or equal expression. Ex: column <= value
+func Lte(column string, value interface{}) Expr {
+	return clause.Lte{Column: column, Value: value}
+}
+
+// GtIfPresent clauses the greater than expression unless the value is nil or a nil pointer.
+func GtIfPresent(column string, value interface{}) Expr {
+	if v, ok := present(value); ok {
+		return Gt(column, v)
+	}
+	return nil
+}
+
+// GteIfPresent clauses the greater than or equal expression unless the value is nil or a nil pointer.
+func GteIfPresent(column string, value interface{}) Expr {
+	if v, ok := present(value); ok {
+		return Gte(column, v)
+	}
+	return nil
+}
+
+// LtIfPresent clauses the less than expression unless the value is nil or a nil pointer.
+func LtIfPresent(column string, value interface{}) Expr {
+	if v, ok := present(value); ok {
+		return Lt(column, v)
+	}
+	return nil
+}
+
+// LteIfPresent clauses the less than or equal expression unless the value is nil or a nil pointer.
+func LteIfPresent(column string, value interface{}) Expr {
+	if v, ok := present(value); ok {
+		return Lte(column, v)
+	}
+	return nil
+}
+
+// Between clauses the inclusive range expression. Ex: column BETWEEN from AND to
+func Between(column string, from, to interface{}) Expr {
+	return clause.Expr{SQL: "? BETWEEN ? AND ?", Vars: []interface{}{clause.Column{Name: column}, from, to}}
+}
+
+// BetweenIfPresent clauses the inclusive range on the present bounds, the range is open
+// on a nil bound and skipped when both bounds are nil.
+func BetweenIfPresent(column string, from, to interface{}) Expr {
+	f, hasFrom := present(from)
+	t, hasTo := present(to)
+	switch {
+	case hasFrom && hasTo:
+		return Between(column, f, t)
+	case hasFrom:
+		return Gte(column, f)
+	case hasTo:
+		return Lte(column, t)
+	}
+	return nil
+}
+
+// Overlaps clauses the rows whose window [startColumn, endColumn] overlaps the inclusive window [from, to].
+// Ex: startColumn <= to AND endColumn >= from
+func Overlaps(startColumn, endColumn string, from, to interface{}) Expr {
+	return And(Lte(startColumn, to), Gte(endColumn, from))
+}
+
+// OverlapsIfPresent clauses the overlapping windows on the present bounds, the window is open
+// on a nil bound and skipped when both bounds are nil.
+func OverlapsIfPresent(startColumn, endColumn string, from, to interface{}) Expr {
+	f, hasFrom := present(from)
+	t, hasTo := present(to)
+	if !hasFrom && !hasTo {
+		return nil
+	}
+	var exprs []Expr
+	if hasTo {
+		exprs = append(exprs, Lte(startColumn, t))
+	}
+	if hasFrom {
+		exprs = append(exprs, Gte(endColumn, f))
+	}
+	return And(exprs...)
+}
+
+// Exists clauses the subquery existence. Ex: EXISTS (SELECT ...)
+// The subquery is a *query.Query or a *gorm.DB statement.
+func Exists(subquery interface{}) Expr {
+	return clause.Expr{SQL: "EXISTS (?)", Vars: []interface{}{subquery}}
+}
+
+// NotExists clauses the subquery absence. Ex: NOT EXISTS (SELECT ...)
+func NotExists(subquery interface{}) Expr {
+	return clause.Expr{SQL: "NOT EXISTS (?)", Vars: []interface{}{subquery}}
+}
+
+// InSub clauses the column values in the subquery results. Ex: column IN (SELECT ...)
+func InSub(column string, subquery interface{}) Expr {
+	return clause.Expr{SQL: "? IN (?)", Vars: []interface{}{clause.Column{Name: column}, subquery}}
+}
+
+// NotInSub clauses the column values not in the subquery results. Ex: column NOT IN (SELECT ...)
+func NotInSub(column string, subquery interface{}) Expr {
+	return clause.Expr{SQL: "? NOT IN (?)", Vars: []interface{}{clause.Column{Name: column}, subquery}}
+}
+
 // SQLTimeExp clauses is SQL express with the datetime
 func SQLTimeExp(sql string, value *time.Time, layout string) Expr {
 	if value != nil && len(layout) > 0 {
@@ -94,6 +221,20 @@ func AlwaysTrue() Expr {
 	return clause.NamedExpr{SQL: "1 = 1"}
 }
 
+// present returns the value, dereferenced when it is a pointer, unless it is nil or a nil pointer.
+func present(value interface{}) (interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, false
+		}
+		return rv.Elem().Interface(), true
+	}
+	return value, true
+}
+
 func ignoreNilExprs(exprs []Expr) []Expr {
 	var ignoreNilExpr []Expr
 	for _, e := range exprs {
@@ -0,0 +1,197 @@
+// Package filter parses the filter query strings of the REST endpoints into criteria expressions.
+//
+// Each filter parameter takes the form field=op:value, e.g.
+//
+//	?status=in:1,2&startDate=gte:2024-01-01&title=like:foo
+//
+// A value without a known operator prefix is compared for equality. The filtered fields, their
+// columns, value types and operators are declared per endpoint by Fields.
+package filter
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mauricetjmurphy/ms-common/clients/http"
+	"github.com/mauricetjmurphy/ms-common/db/query/criteria"
+)
+
+// DateLayout is the layout of the Date values.
+const DateLayout = "2006-01-02"
+
+// Type is the value type of a filtered field.
+type Type int
+
+const (
+	String Type = iota
+	Int
+	Uint
+	Float
+	Bool
+	// Date values are formatted as DateLayout.
+	Date
+	// Time values are formatted as RFC 3339.
+	Time
+)
+
+// Op is the filter operator.
+type Op string
+
+const (
+	Eq      Op = "eq"
+	NotEq   Op = "ne"
+	Gt      Op = "gt"
+	Gte     Op = "gte"
+	Lt      Op = "lt"
+	Lte     Op = "lte"
+	In      Op = "in"
+	NotIn   Op = "nin"
+	Like    Op = "like"
+	Between Op = "between"
+)
+
+var ops = map[Op]bool{Eq: true, NotEq: true, Gt: true, Gte: true, Lt: true, Lte: true, In: true, NotIn: true, Like: true, Between: true}
+
+// Field declares a filtered field.
+type Field struct {
+	// Column is the filtered column, e.g. dd.Status.
+	Column string
+	// Type is the type the values are parsed as.
+	Type Type
+	// Ops whitelists the operators of the field, all operators are allowed when empty.
+	Ops []Op
+}
+
+// Fields declares the filtered fields of an endpoint keyed by their query parameter name.
+// The parameters not declared, e.g. the pagination ones, are ignored.
+type Fields map[string]Field
+
+// Parse parses the declared filter parameters into the criteria expression, the filters being
+// combined with AND. It returns a nil expression when no filter is present and a clients/http
+// BadRequest error on the invalid filters.
+func (f Fields) Parse(values url.Values) (criteria.Expr, error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		if _, ok := f[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var exprs []criteria.Expr
+	for _, name := range names {
+		for _, raw := range values[name] {
+			expr, err := f[name].parse(name, raw)
+			if err != nil {
+				return nil, err
+			}
+			exprs = append(exprs, expr)
+		}
+	}
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	return criteria.And(exprs...), nil
+}
+
+func (fd Field) parse(name, raw string) (criteria.Expr, error) {
+	op, value := Eq, raw
+	if i := strings.Index(raw, ":"); i >= 0 && ops[Op(raw[:i])] {
+		op, value = Op(raw[:i]), raw[i+1:]
+	}
+	if !fd.allows(op) {
+		return nil, http.BadRequest(fmt.Sprintf("filter %v: operator %v is not allowed", name, op))
+	}
+
+	switch op {
+	case In, NotIn, Between:
+		var args []interface{}
+		for _, v := range strings.Split(value, ",") {
+			arg, err := fd.value(name, v)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		switch op {
+		case In:
+			return criteria.In(fd.Column, args...), nil
+		case NotIn:
+			return criteria.NotIn(fd.Column, args...), nil
+		}
+		if len(args) != 2 {
+			return nil, http.BadRequest(fmt.Sprintf("filter %v: between expects 2 values, got %v", name, len(args)))
+		}
+		return criteria.Between(fd.Column, args[0], args[1]), nil
+	case Like:
+		if fd.Type != String {
+			return nil, http.BadRequest(fmt.Sprintf("filter %v: operator like is only allowed on strings", name))
+		}
+		if len(value) == 0 {
+			return nil, http.BadRequest(fmt.Sprintf("filter %v: missing value", name))
+		}
+		return criteria.Wildcard(fd.Column, value), nil
+	}
+
+	arg, err := fd.value(name, value)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case NotEq:
+		return criteria.NotEq(fd.Column, arg), nil
+	case Gt:
+		return criteria.Gt(fd.Column, arg), nil
+	case Gte:
+		return criteria.Gte(fd.Column, arg), nil
+	case Lt:
+		return criteria.Lt(fd.Column, arg), nil
+	case Lte:
+		return criteria.Lte(fd.Column, arg), nil
+	}
+	return criteria.Eq(fd.Column, arg), nil
+}
+
+func (fd Field) allows(op Op) bool {
+	if len(fd.Ops) == 0 {
+		return true
+	}
+	for _, o := range fd.Ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// value parses the raw value as the field type.
+func (fd Field) value(name, raw string) (interface{}, error) {
+	var (
+		v   interface{}
+		err error
+	)
+	switch fd.Type {
+	case Int:
+		v, err = strconv.ParseInt(raw, 10, 64)
+	case Uint:
+		v, err = strconv.ParseUint(raw, 10, 64)
+	case Float:
+		v, err = strconv.ParseFloat(raw, 64)
+	case Bool:
+		v, err = strconv.ParseBool(raw)
+	case Date:
+		v, err = time.Parse(DateLayout, raw)
+	case Time:
+		v, err = time.Parse(time.RFC3339, raw)
+	default:
+		v = raw
+	}
+	if err != nil {
+		return nil, http.BadRequest(fmt.Sprintf("filter %v: invalid value %q", name, raw))
+	}
+	return v, nil
+}
@@ -0,0 +1,102 @@
+package filter_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/mauricetjmurphy/ms-common/clients/http"
+	"github.com/mauricetjmurphy/ms-common/db/query/criteria"
+	"github.com/mauricetjmurphy/ms-common/db/query/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+var fields = filter.Fields{
+	"status":    {Column: "dd.Status", Type: filter.Int, Ops: []filter.Op{filter.Eq, filter.In, filter.Like}},
+	"startDate": {Column: "dd.StartDate", Type: filter.Date},
+	"title":     {Column: "dd.Title"},
+	"active":    {Column: "dd.Active", Type: filter.Bool},
+}
+
+func TestFields_Parse(t *testing.T) {
+	startDate := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name       string
+		query      string
+		want       criteria.Expr
+		wantStatus int
+		wantMsg    string
+	}{
+		{
+			name:  "Parse_NoFilters",
+			query: "pageNumber=1",
+			want:  nil,
+		},
+		{
+			name:  "Parse_Operators",
+			query: "status=in:1,2&startDate=gte:2024-01-01&title=like:foo&pageSize=10",
+			want: criteria.And(
+				criteria.Gte("dd.StartDate", startDate),
+				criteria.In("dd.Status", int64(1), int64(2)),
+				criteria.Wildcard("dd.Title", "foo"),
+			),
+		},
+		{
+			name:  "Parse_DefaultEq_UnknownPrefix",
+			query: "title=foo:bar&active=true",
+			want: criteria.And(
+				criteria.Eq("dd.Active", true),
+				criteria.Eq("dd.Title", "foo:bar"),
+			),
+		},
+		{
+			name:  "Parse_Between",
+			query: "startDate=between:2024-01-01,2024-01-31",
+			want:  criteria.And(criteria.Between("dd.StartDate", startDate, endDate)),
+		},
+		{
+			name:       "Parse_InvalidValue",
+			query:      "status=abc",
+			wantStatus: 400,
+		},
+		{
+			name:       "Parse_OperatorNotAllowed",
+			query:      "status=gt:1",
+			wantStatus: 400,
+		},
+		{
+			name:       "Parse_Between_WrongArity",
+			query:      "startDate=between:2024-01-01",
+			wantStatus: 400,
+		},
+		{
+			name:       "Parse_Like_NotString",
+			query:      "status=like:1",
+			wantStatus: 400,
+			wantMsg:    "operator like is only allowed on strings",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			values, err := url.ParseQuery(tt.query)
+			assert.NoError(t, err)
+
+			//When
+			got, err := fields.Parse(values)
+
+			//Then
+			if tt.wantStatus > 0 {
+				if assert.IsType(t, &http.Error{}, err) {
+					assert.Equal(t, tt.wantStatus, err.(*http.Error).StatusCode)
+					assert.Contains(t, err.(*http.Error).Message, tt.wantMsg)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package query
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Each finds the records matching given criteria in batches of batchSize, loading each batch into
+// value, a pointer to a slice, and calling fn on it until all the records are read, fn returns an
+// error or the context is done. The batches are read by keyset pagination on the Keyset columns,
+// or on the primary key when not set; Order, Page, Offset and Limit are ignored.
+func (q *Query) Each(ctx context.Context, batchSize int, value interface{}, fn func() error) error {
+	if batchSize <= 0 {
+		return errors.New("query : batch size must be positive")
+	}
+	columns, err := q.eachColumns(value)
+	if err != nil {
+		return err
+	}
+
+	var cursor string
+	for {
+		if ctx != nil {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		page, err := q.withKeyset(&keyset{cursor: cursor, limit: batchSize, columns: columns, skipCount: true}).
+			FindPage(ctx, value)
+		if err != nil {
+			return err
+		}
+		if reflect.Indirect(reflect.ValueOf(value)).Len() == 0 {
+			return nil
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+		if page.Next == "" {
+			return nil
+		}
+		cursor = page.Next
+	}
+}
+
+// eachColumns returns the keyset columns of Each, defaulting to the primary key.
+func (q *Query) eachColumns(value interface{}) ([]KeysetColumn, error) {
+	if q.keyset != nil && len(q.keyset.columns) > 0 {
+		return q.keyset.columns, nil
+	}
+	stmt := &gorm.Statement{DB: q.db}
+	if err := stmt.Parse(value); err != nil {
+		return nil, errors.Wrap(err, "query : failed to parse model")
+	}
+	pk := stmt.Schema.PrioritizedPrimaryField
+	if pk == nil {
+		return nil, gorm.ErrPrimaryKeyRequired
+	}
+	table := q.alias
+	if table == "" {
+		table = q.from
+	}
+	if table == "" {
+		table = stmt.Schema.Table
+	}
+	return []KeysetColumn{{Column: table + "." + pk.DBName, Field: pk.Name}}, nil
+}
+
+// withKeyset returns a copy of the query paginated by ks, the query itself being left unchanged.
+func (q *Query) withKeyset(ks *keyset) *Query {
+	s := *q
+	s.keyset = ks
+	return &s
+}
+
+// Iterator iterates the query results one record at a time, reading them in batches.
+type Iterator struct {
+	q         *Query
+	ctx       context.Context
+	batchSize int
+	columns   []KeysetColumn
+	cursor    string
+	batch     reflect.Value
+	index     int
+	loaded    bool
+	done      bool
+	err       error
+}
+
+// Iterate returns the iterator on the records matching given criteria, reading batchSize records
+// at a time. The records are ordered as by Each.
+func (q *Query) Iterate(ctx context.Context, batchSize int) *Iterator {
+	it := &Iterator{q: q, ctx: ctx, batchSize: batchSize}
+	if batchSize <= 0 {
+		it.err = errors.New("query : batch size must be positive")
+	}
+	return it
+}
+
+// Next loads the next record into dest, a pointer to the record type, e.g. *Entity or **Entity.
+// It returns false when there are no more records or on error, see Err.
+func (it *Iterator) Next(dest interface{}) bool {
+	if it.err != nil || it.done {
+		return false
+	}
+	target := reflect.ValueOf(dest)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		it.err = errors.Errorf("query : iterator destination must be a non nil pointer, got %T", dest)
+		return false
+	}
+	if !it.batch.IsValid() {
+		it.batch = reflect.New(reflect.SliceOf(target.Elem().Type()))
+		if it.columns, it.err = it.q.eachColumns(it.batch.Interface()); it.err != nil {
+			return false
+		}
+	}
+
+	for it.index >= it.batch.Elem().Len() {
+		// the last batch has been read.
+		if it.loaded && it.cursor == "" {
+			it.done = true
+			return false
+		}
+		if !it.fetch() {
+			return false
+		}
+	}
+	target.Elem().Set(it.batch.Elem().Index(it.index))
+	it.index++
+	return true
+}
+
+// fetch reads the next batch.
+func (it *Iterator) fetch() bool {
+	if it.ctx != nil {
+		if it.err = it.ctx.Err(); it.err != nil {
+			return false
+		}
+	}
+	page, err := it.q.withKeyset(&keyset{cursor: it.cursor, limit: it.batchSize, columns: it.columns, skipCount: true}).
+		FindPage(it.ctx, it.batch.Interface())
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.index, it.cursor, it.loaded = 0, page.Next, true
+	return true
+}
+
+// Err returns the error which stopped the iteration.
+func (it *Iterator) Err() error {
+	return it.err
+}
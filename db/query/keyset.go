@@ -0,0 +1,249 @@
+package query
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"github.com/mauricetjmurphy/ms-common/db/query/criteria"
+)
+
+// TotalUnknown is the page total when the count query is skipped.
+const TotalUnknown int64 = -1
+
+// ErrInvalidCursor is returned when the keyset cursor cannot be decoded.
+var ErrInvalidCursor = errors.New("query : invalid keyset cursor")
+
+// KeysetColumn is one ordering column of the keyset pagination.
+// The columns must be not null and, all together, unique, e.g. ending with the primary key.
+type KeysetColumn struct {
+	// Column is the ordering column, e.g. dd.StartDate.
+	Column string
+	// Field is the model field or column name holding the column value in the results,
+	// defaults to the Column name without its table alias.
+	Field string
+	// Desc orders the column descending.
+	Desc bool
+}
+
+// KeysetPage is one page of a keyset pagination.
+type KeysetPage struct {
+	// Total is the number of records matching the criteria, or TotalUnknown when the count is skipped.
+	Total int64
+	// Next is the cursor of the next page, empty on the last page.
+	Next string
+	// Prev is the cursor of the previous page, empty on the first page.
+	Prev string
+}
+
+type keyset struct {
+	cursor    string
+	limit     int
+	columns   []KeysetColumn
+	skipCount bool
+}
+
+// cursor is the decoded keyset cursor: the ordering column values of the boundary row
+// and whether the page is read backward from it.
+type cursor struct {
+	Values   []json.RawMessage `json:"v"`
+	Backward bool              `json:"b,omitempty"`
+}
+
+// Keyset provides the keyset (cursor) pagination on given ordering columns, returning limit rows
+// after the encoded cursor, or the first page when the cursor is empty. The keyset order replaces
+// Order, Page, Offset and Limit. The page is read by FindPage.
+func (q *Query) Keyset(cursor string, limit int, columns ...KeysetColumn) *Query {
+	skipCount := q.keyset != nil && q.keyset.skipCount
+	q.keyset = &keyset{
+		cursor:    cursor,
+		limit:     limit,
+		columns:   columns,
+		skipCount: skipCount,
+	}
+	return q
+}
+
+// SkipCount skips the count query of FindPage, the page total is then TotalUnknown.
+func (q *Query) SkipCount(skip bool) *Query {
+	if q.keyset == nil {
+		q.keyset = &keyset{}
+	}
+	q.keyset.skipCount = skip
+	return q
+}
+
+// FindPage finds the keyset page of records matching given criteria.
+func (q *Query) FindPage(ctx context.Context, value interface{}) (*KeysetPage, error) {
+	ks := q.keyset
+	if ks == nil || len(ks.columns) == 0 || ks.limit <= 0 {
+		return nil, errors.New("query : keyset columns and limit are required")
+	}
+
+	fields, err := q.keysetFields(value)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := decodeCursor(ks.cursor, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx == nil {
+		ctx = q.db.Statement.Context
+	}
+	eg, egCtx := errgroup.WithContext(ctx)
+	s := q.session(egCtx).withModel(value)
+
+	page := &KeysetPage{Total: TotalUnknown}
+	if !ks.skipCount {
+		eg.Go(func() error {
+			return s.getTotalRecords(&page.Total)
+		})
+	}
+	eg.Go(func() error {
+		tx := s.Clause().Limit(ks.limit + 1)
+		if cur != nil {
+			tx = tx.Clauses(clause.Where{Exprs: []clause.Expression{ks.after(cur)}})
+		}
+		for _, c := range ks.columns {
+			tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: c.Column}, Desc: c.Desc != (cur != nil && cur.Backward)})
+		}
+		return tx.Find(value).Error
+	})
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	rows := reflect.Indirect(reflect.ValueOf(value))
+	hasMore := rows.Len() > ks.limit
+	if hasMore {
+		rows.Set(rows.Slice(0, ks.limit))
+	}
+	backward := cur != nil && cur.Backward
+	if backward {
+		reverse(rows)
+	}
+	if rows.Len() == 0 {
+		return page, nil
+	}
+
+	// Forward pages have a previous page unless read from the start, backward pages
+	// have a next page as they were read back from it.
+	if backward || hasMore {
+		if page.Next, err = encodeCursor(ctx, fields, rows.Index(rows.Len()-1), false); err != nil {
+			return nil, err
+		}
+	}
+	if (backward && hasMore) || (!backward && cur != nil) {
+		if page.Prev, err = encodeCursor(ctx, fields, rows.Index(0), true); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// keysetFields resolves the model fields holding the ordering column values.
+func (q *Query) keysetFields(value interface{}) ([]*schema.Field, error) {
+	stmt := &gorm.Statement{DB: q.db}
+	if err := stmt.Parse(value); err != nil {
+		return nil, errors.Wrap(err, "query : failed to parse keyset model")
+	}
+	var fields []*schema.Field
+	for _, c := range q.keyset.columns {
+		name := c.Field
+		if name == "" {
+			name = c.Column[strings.LastIndex(c.Column, ".")+1:]
+		}
+		field := stmt.Schema.LookUpField(name)
+		if field == nil {
+			return nil, errors.Errorf("query : unknown keyset field %v of %v", name, stmt.Schema.Name)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// after builds the row comparison (c1, c2, ...) > (v1, v2, ...) as the expanded form
+// c1 > v1 OR (c1 = v1 AND c2 > v2) OR ..., flipping the comparison on descending columns.
+func (ks *keyset) after(cur *keysetCursor) criteria.Expr {
+	var ors []criteria.Expr
+	for i, c := range ks.columns {
+		var ands []criteria.Expr
+		for j := 0; j < i; j++ {
+			ands = append(ands, criteria.Eq(ks.columns[j].Column, cur.values[j]))
+		}
+		if c.Desc != cur.Backward {
+			ands = append(ands, clause.Lt{Column: c.Column, Value: cur.values[i]})
+		} else {
+			ands = append(ands, clause.Gt{Column: c.Column, Value: cur.values[i]})
+		}
+		ors = append(ors, criteria.And(ands...))
+	}
+	// a single OR condition would be joined with OR to the query where clauses.
+	if len(ors) == 1 {
+		return ors[0]
+	}
+	return criteria.Or(ors...)
+}
+
+// keysetCursor is the decoded cursor with the values typed as their model fields.
+type keysetCursor struct {
+	values   []interface{}
+	Backward bool
+}
+
+func decodeCursor(encoded string, fields []*schema.Field) (*keysetCursor, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cur cursor
+	if err := json.Unmarshal(data, &cur); err != nil || len(cur.Values) != len(fields) {
+		return nil, ErrInvalidCursor
+	}
+	decoded := &keysetCursor{Backward: cur.Backward}
+	for i, field := range fields {
+		v := reflect.New(field.FieldType)
+		if err := json.Unmarshal(cur.Values[i], v.Interface()); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		decoded.values = append(decoded.values, v.Elem().Interface())
+	}
+	return decoded, nil
+}
+
+func encodeCursor(ctx context.Context, fields []*schema.Field, row reflect.Value, backward bool) (string, error) {
+	cur := cursor{Backward: backward}
+	for _, field := range fields {
+		v, _ := field.ValueOf(ctx, reflect.Indirect(row))
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return "", errors.Wrap(err, "query : failed to encode keyset cursor")
+		}
+		cur.Values = append(cur.Values, raw)
+	}
+	data, err := json.Marshal(cur)
+	if err != nil {
+		return "", errors.Wrap(err, "query : failed to encode keyset cursor")
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func reverse(rows reflect.Value) {
+	swap := reflect.Swapper(rows.Interface())
+	for i, j := 0, rows.Len()-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+}
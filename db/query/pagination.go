@@ -0,0 +1,94 @@
+package query
+
+import (
+	"github.com/mauricetjmurphy/ms-common/db/query/criteria"
+	"github.com/mauricetjmurphy/ms-common/types/settings/pagination"
+)
+
+const (
+	// DefaultPageSize is the page size applied when the pagination has none.
+	DefaultPageSize int64 = 20
+	// MaxPageSize is the upper bound of the requested page size.
+	MaxPageSize int64 = 100
+)
+
+// Pager applies the pagination requests on the queries.
+type Pager struct {
+	// Columns whitelists the sort fields, mapping each of them to its ordering column, e.g. "name": "dd.Name".
+	Columns map[string]string
+	// DefaultSort is the sort field applied when the requested one is empty or not whitelisted.
+	DefaultSort string
+	// DefaultSize is the page size applied when the requested one is not set, defaults to DefaultPageSize.
+	DefaultSize int64
+	// MaxSize caps the requested page size, defaults to MaxPageSize.
+	MaxSize int64
+}
+
+// Paged is the paged response envelope of a pagination request.
+type Paged struct {
+	// Total is the number of records matching the criteria.
+	Total int64 `json:"total"`
+	// Pages is the number of pages of the records.
+	Pages int64 `json:"pages"`
+	// PageNumber is the zero based number of the returned page.
+	PageNumber int64 `json:"pageNumber"`
+	// PageSize is the applied page size.
+	PageSize int64 `json:"pageSize"`
+}
+
+// Paginate applies given pagination request on the query page and order.
+func (q *Query) Paginate(p *pagination.Pagination, pager Pager) *Query {
+	q.Page(pager.PageNumber(p), pager.PageSize(p))
+	if column := pager.Column(p); len(column) > 0 {
+		q.Order(criteria.Order(column, p.GetOrder().String()))
+	}
+	return q
+}
+
+// PageNumber returns the requested zero based page number, never negative.
+func (pg Pager) PageNumber(p *pagination.Pagination) int64 {
+	if n := p.GetPageNumber(); n > 0 {
+		return n
+	}
+	return 0
+}
+
+// PageSize returns the requested page size bounded by the pager default and max sizes.
+func (pg Pager) PageSize(p *pagination.Pagination) int64 {
+	size := p.GetPageSize()
+	if size <= 0 {
+		size = pg.DefaultSize
+		if size <= 0 {
+			size = DefaultPageSize
+		}
+	}
+	max := pg.MaxSize
+	if max <= 0 {
+		max = MaxPageSize
+	}
+	if size > max {
+		return max
+	}
+	return size
+}
+
+// Column returns the ordering column of the requested sort field, or of the default sort
+// when the requested one is not whitelisted.
+func (pg Pager) Column(p *pagination.Pagination) string {
+	if column, ok := pg.Columns[p.GetSort()]; ok {
+		return column
+	}
+	return pg.Columns[pg.DefaultSort]
+}
+
+// Paged builds the paged response envelope of given pagination request from the total
+// records returned by FindAll.
+func (pg Pager) Paged(p *pagination.Pagination, total int64) *Paged {
+	size := pg.PageSize(p)
+	return &Paged{
+		Total:      total,
+		Pages:      (total + size - 1) / size,
+		PageNumber: pg.PageNumber(p),
+		PageSize:   size,
+	}
+}
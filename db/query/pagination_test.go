@@ -0,0 +1,106 @@
+package query_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mauricetjmurphy/ms-common/db/dbmocks"
+	q "github.com/mauricetjmurphy/ms-common/db/query"
+	"github.com/mauricetjmurphy/ms-common/types/settings/pagination"
+	"github.com/stretchr/testify/assert"
+)
+
+var dummyPager = q.Pager{
+	Columns: map[string]string{
+		"id":   "dd.Id",
+		"name": "dd.Name",
+	},
+	DefaultSort: "id",
+	DefaultSize: 10,
+	MaxSize:     50,
+}
+
+func TestQuery_Paginate(t *testing.T) {
+	cases := []struct {
+		name       string
+		pagination *pagination.Pagination
+		wantSQL    string
+	}{
+		{
+			name:       "Paginate_Nil_Defaults",
+			pagination: nil,
+			wantSQL:    "SELECT dd.* FROM dummy AS dd ORDER BY dd.Id ASC LIMIT 10",
+		},
+		{
+			name:       "Paginate_WhitelistedSort",
+			pagination: &pagination.Pagination{PageNumber: 2, PageSize: 5, Order: pagination.OrderType_DESC, Sort: "name"},
+			wantSQL:    "SELECT dd.* FROM dummy AS dd ORDER BY dd.Name DESC LIMIT 5 OFFSET 10",
+		},
+		{
+			name:       "Paginate_UnknownSort_MaxSize",
+			pagination: &pagination.Pagination{PageNumber: 1, PageSize: 1000, Sort: "Name; DROP TABLE dummy"},
+			wantSQL:    "SELECT dd.* FROM dummy AS dd ORDER BY dd.Id ASC LIMIT 50 OFFSET 50",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			dbMock, sqlMock := dbmocks.NewSqlMock()
+			sqlMock.ExpectQuery(regexp.QuoteMeta(tt.wantSQL)).
+				WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(1, "Test", 1))
+			var results []*DummyTable
+
+			//When
+			err := q.New(dbMock).
+				Select("dd.*").
+				From(dummyTable, "dd").
+				Paginate(tt.pagination, dummyPager).
+				Find(context.Background(), &results)
+
+			//Then
+			assert.NoError(t, err)
+			if err := sqlMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestPager_Paged(t *testing.T) {
+	cases := []struct {
+		name       string
+		pagination *pagination.Pagination
+		total      int64
+		want       *q.Paged
+	}{
+		{
+			name:       "Paged_Empty",
+			pagination: &pagination.Pagination{},
+			total:      0,
+			want:       &q.Paged{Total: 0, Pages: 0, PageNumber: 0, PageSize: 10},
+		},
+		{
+			name:       "Paged_PartialLastPage",
+			pagination: &pagination.Pagination{PageNumber: 1, PageSize: 20},
+			total:      41,
+			want:       &q.Paged{Total: 41, Pages: 3, PageNumber: 1, PageSize: 20},
+		},
+		{
+			name:       "Paged_NegativePageNumber",
+			pagination: &pagination.Pagination{PageNumber: -1, PageSize: 5},
+			total:      10,
+			want:       &q.Paged{Total: 10, Pages: 2, PageNumber: 0, PageSize: 5},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//When
+			got := dummyPager.Paged(tt.pagination, tt.total)
+
+			//Then
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
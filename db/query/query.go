@@ -3,6 +3,7 @@ package query
 import (
 	"context"
 
+	"github.com/mauricetjmurphy/ms-common/db/entity"
 	"github.com/mauricetjmurphy/ms-common/db/query/criteria"
 
 	"golang.org/x/sync/errgroup"
@@ -15,8 +16,10 @@ import (
 //
 //go:generate mockery --output querymocks --outpkg querymocks --name Querier
 type Querier interface {
+	Each(context.Context, int, interface{}, func() error) error
 	Find(context.Context, interface{}) error
 	FindAll(context.Context, interface{}) (int64, error)
+	FindPage(context.Context, interface{}) (*KeysetPage, error)
 }
 
 type Query struct {
@@ -26,6 +29,7 @@ type Query struct {
 	distinct      bool
 	countDistinct []string
 	from          string
+	alias         string
 	innerJoin     []Join
 	leftJoin      []Join
 	where         []criteria.Expr
@@ -35,6 +39,9 @@ type Query struct {
 	limit         int
 	preloads      []string
 	preloadFunc   map[string]criteria.Expr
+	keyset        *keyset
+	withDeleted   bool
+	onlyDeleted   bool
 }
 
 // New create the query instance on given client DB.
@@ -46,21 +53,24 @@ func New(db *gorm.DB) *Query {
 }
 
 func (q *Query) FindAll(ctx context.Context, value interface{}) (int64, error) {
-	q.context(ctx)
+	var count int64
 
-	var (
-		eg    errgroup.Group
-		count int64
-	)
+	if ctx == nil {
+		ctx = q.db.Statement.Context
+	}
+	// Abandon the sibling statement as soon as one of them fails.
+	eg, egCtx := errgroup.WithContext(ctx)
+	// Set the model before the sibling statements share the query.
+	s := q.session(egCtx).withModel(value)
 
 	eg.Go(func() error {
 		// Count the total records matching given criteria
-		return q.getTotalRecords(value, &count)
+		return s.getTotalRecords(&count)
 	})
 
 	eg.Go(func() error {
 		// Find paged records matching given criteria
-		result := q.buildClauses(value).Find(value)
+		result := s.Clause().Find(value)
 		return result.Error
 	})
 
@@ -72,8 +82,7 @@ func (q *Query) FindAll(ctx context.Context, value interface{}) (int64, error) {
 }
 
 func (q *Query) Find(ctx context.Context, value interface{}) error {
-	q.context(ctx)
-	result := q.buildClauses(value).Find(value)
+	result := q.session(ctx).buildClauses(value).Find(value)
 	if err := result.Error; err != nil {
 		return err
 	}
@@ -82,6 +91,9 @@ func (q *Query) Find(ctx context.Context, value interface{}) error {
 
 func (q *Query) Clause() *gorm.DB {
 	tx := q.db.Model(q.model)
+	if q.withDeleted || q.onlyDeleted {
+		tx.Unscoped()
+	}
 
 	tx.Select(q.fields)
 	if q.distinct {
@@ -101,12 +113,19 @@ func (q *Query) Clause() *gorm.DB {
 		})
 	}
 
-	if q.limit > 0 {
+	if q.onlyDeleted {
+		tx.Clauses(clause.Where{Exprs: []clause.Expression{
+			clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: entity.DeletedDatimColumn}, Value: nil},
+		}})
+	}
+
+	// The keyset pagination provides its own order and limit.
+	if q.limit > 0 && q.keyset == nil {
 		tx.Limit(q.limit)
 		tx.Offset(q.offset)
 	}
 
-	if len(q.order) > 0 {
+	if len(q.order) > 0 && q.keyset == nil {
 		tx.Order(q.order)
 	}
 
@@ -129,10 +148,14 @@ func (q *Query) Clause() *gorm.DB {
 	return tx
 }
 
-func (q *Query) context(ctx context.Context) {
+// session returns a copy of the query running its statements on ctx, the query itself being
+// left reusable once ctx is done.
+func (q *Query) session(ctx context.Context) *Query {
+	s := *q
 	if ctx != nil {
-		q.db.WithContext(ctx)
+		s.db = q.db.WithContext(ctx)
 	}
+	return &s
 }
 
 func (q *Query) withModel(model interface{}) *Query {
@@ -150,10 +173,15 @@ func (q *Query) withJoin() []clause.Join {
 	return append(inner, left...)
 }
 
-func (q *Query) getTotalRecords(value interface{}, count *int64) error {
-	cli := q.buildClauses(value)
+func (q *Query) getTotalRecords(count *int64) error {
+	cli := q.Clause()
 	if q.distinct && len(q.countDistinct) > 0 {
 		cli.Distinct(q.countDistinct)
 	}
 	return cli.Count(count).Error
 }
+
+// Build embeds the query as a subquery expression, e.g. criteria.Exists(sub) or criteria.InSub(column, sub).
+func (q *Query) Build(builder clause.Builder) {
+	builder.AddVar(builder, q.Clause())
+}
@@ -2,7 +2,10 @@ package query_test
 
 import (
 	"context"
+	"encoding/base64"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/mauricetjmurphy/ms-common/db/dbmocks"
 
@@ -12,6 +15,7 @@ import (
 	c "github.com/mauricetjmurphy/ms-common/db/query/criteria"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const (
@@ -588,3 +592,559 @@ func TestQuery_Find_Utils(t *testing.T) {
 		})
 	}
 }
+
+func TestQuery_Find_ContextCanceled(t *testing.T) {
+	//Given
+	dbMock, sqlMock := dbmocks.NewSqlMock()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var results []*DummyTable
+
+	//When
+	err := q.New(dbMock).
+		Select("dd.*").
+		From(dummyTable, "dd").
+		Find(ctx, &results)
+
+	//Then
+	assert.ErrorIs(t, err, context.Canceled)
+	if err := sqlMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestQuery_FindAll_Reuse(t *testing.T) {
+	//Given
+	dbMock, sqlMock := dbmocks.NewSqlMock()
+	sqlMock.MatchExpectationsInOrder(false)
+	sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(`dd`.`*`) FROM dummy AS dd")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	for i := 0; i < 2; i++ {
+		sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd")).
+			WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(1, "Test", 1))
+	}
+	query := q.New(dbMock).Select("dd.*").From(dummyTable, "dd")
+	var first, second []*DummyTable
+	_, err := query.FindAll(context.Background(), &first)
+	assert.NoError(t, err)
+
+	//When
+	err = query.Clause().Find(&second).Error
+
+	//Then
+	assert.NoError(t, err)
+	assert.Len(t, second, 1)
+	if err := sqlMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestQuery_Find_Deadline(t *testing.T) {
+	//Given
+	dbMock, sqlMock := dbmocks.NewSqlMock()
+	sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd")).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(1, "Test", 1))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var results []*DummyTable
+	begin := time.Now()
+
+	//When
+	err := q.New(dbMock).
+		Select("dd.*").
+		From(dummyTable, "dd").
+		Find(ctx, &results)
+
+	//Then
+	assert.Error(t, err)
+	assert.Less(t, time.Since(begin), time.Second)
+	assert.Empty(t, results)
+}
+
+func TestQuery_FindPage(t *testing.T) {
+	keysetCols := []q.KeysetColumn{
+		{Column: "dd.Name"},
+		{Column: "dd.Id"},
+	}
+	afterCursor := base64.RawURLEncoding.EncodeToString([]byte(`{"v":["Test",1]}`))
+	beforeCursor := base64.RawURLEncoding.EncodeToString([]byte(`{"v":["Test",2],"b":true}`))
+	idCursor := base64.RawURLEncoding.EncodeToString([]byte(`{"v":[1]}`))
+
+	cases := []struct {
+		name      string
+		fnMocks   func(sqlMock sqlmock.Sqlmock)
+		query     func(db *gorm.DB) q.Querier
+		wantErr   error
+		wantTotal int64
+		wantIDs   []uint
+		wantNext  bool
+		wantPrev  bool
+	}{
+		{
+			name: "FindPage_FirstPage",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.MatchExpectationsInOrder(false)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(`dd`.`*`) FROM dummy AS dd")).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+				sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd ORDER BY `dd`.`Name`,`dd`.`Id` LIMIT 2")).
+					WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(1, "Test", 1).AddRow(2, "Test", 1))
+			},
+			query: func(db *gorm.DB) q.Querier {
+				return q.New(db).
+					Select("dd.*").
+					From(dummyTable, "dd").
+					Keyset("", 1, keysetCols...)
+			},
+			wantTotal: 3,
+			wantIDs:   []uint{1},
+			wantNext:  true,
+		},
+		{
+			name: "FindPage_AfterCursor_SkipCount",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd WHERE (`dd`.`Name` > ? OR (`dd`.`Name` = ? AND `dd`.`Id` > ?)) ORDER BY `dd`.`Name`,`dd`.`Id` LIMIT 3")).
+					WithArgs("Test", "Test", 1).
+					WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(2, "Test", 1).AddRow(3, "Test", 1))
+			},
+			query: func(db *gorm.DB) q.Querier {
+				return q.New(db).
+					Select("dd.*").
+					From(dummyTable, "dd").
+					Keyset(afterCursor, 2, keysetCols...).
+					SkipCount(true)
+			},
+			wantTotal: q.TotalUnknown,
+			wantIDs:   []uint{2, 3},
+			wantPrev:  true,
+		},
+		{
+			name: "FindPage_BeforeCursor_Reversed",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd WHERE (`dd`.`Name` < ? OR (`dd`.`Name` = ? AND `dd`.`Id` < ?)) ORDER BY `dd`.`Name` DESC,`dd`.`Id` DESC LIMIT 2")).
+					WithArgs("Test", "Test", 2).
+					WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(1, "Test", 1))
+			},
+			query: func(db *gorm.DB) q.Querier {
+				return q.New(db).
+					Select("dd.*").
+					From(dummyTable, "dd").
+					Keyset(beforeCursor, 1, keysetCols...).
+					SkipCount(true)
+			},
+			wantTotal: q.TotalUnknown,
+			wantIDs:   []uint{1},
+			wantNext:  true,
+		},
+		{
+			name: "FindPage_AfterCursor_SingleColumn_WithWhereClause",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd WHERE `dd`.`Name` = ? AND `dd`.`Id` > ? ORDER BY `dd`.`Id` LIMIT 3")).
+					WithArgs("Test", 1).
+					WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(2, "Test", 1))
+			},
+			query: func(db *gorm.DB) q.Querier {
+				return q.New(db).
+					Select("dd.*").
+					From(dummyTable, "dd").
+					Where(c.Eq("dd.Name", "Test")).
+					Keyset(idCursor, 2, q.KeysetColumn{Column: "dd.Id"}).
+					SkipCount(true)
+			},
+			wantTotal: q.TotalUnknown,
+			wantIDs:   []uint{2},
+			wantPrev:  true,
+		},
+		{
+			name: "FindPage_AfterCursor_WithWhereClause",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd WHERE `dd`.`OneToId` = ? AND (`dd`.`Name` > ? OR (`dd`.`Name` = ? AND `dd`.`Id` > ?)) ORDER BY `dd`.`Name`,`dd`.`Id` LIMIT 3")).
+					WithArgs(1, "Test", "Test", 1).
+					WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(2, "Test", 1))
+			},
+			query: func(db *gorm.DB) q.Querier {
+				return q.New(db).
+					Select("dd.*").
+					From(dummyTable, "dd").
+					Where(c.Eq("dd.OneToId", 1)).
+					Keyset(afterCursor, 2, keysetCols...).
+					SkipCount(true)
+			},
+			wantTotal: q.TotalUnknown,
+			wantIDs:   []uint{2},
+			wantPrev:  true,
+		},
+		{
+			name:    "FindPage_InvalidCursor",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {},
+			query: func(db *gorm.DB) q.Querier {
+				return q.New(db).
+					Select("dd.*").
+					From(dummyTable, "dd").
+					Keyset("not-a-cursor", 1, keysetCols...)
+			},
+			wantErr: q.ErrInvalidCursor,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			dbMock, sqlMock := dbmocks.NewSqlMock()
+			tt.fnMocks(sqlMock)
+			var results []*DummyTable
+
+			//When
+			page, err := tt.query(dbMock).FindPage(context.Background(), &results)
+
+			//Then
+			assert.Equal(t, tt.wantErr, err)
+			if err := sqlMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			var ids []uint
+			for _, r := range results {
+				ids = append(ids, r.ID)
+			}
+			assert.Equal(t, tt.wantIDs, ids)
+			assert.Equal(t, tt.wantTotal, page.Total)
+			assert.Equal(t, tt.wantNext, page.Next != "")
+			assert.Equal(t, tt.wantPrev, page.Prev != "")
+		})
+	}
+}
+
+func TestQuery_FindPage_NextCursor(t *testing.T) {
+	//Given
+	dbMock, sqlMock := dbmocks.NewSqlMock()
+	sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd ORDER BY `dd`.`Id` DESC LIMIT 2")).
+		WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(5, "Test", 1).AddRow(4, "Test", 1))
+	sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd WHERE `dd`.`Id` < ? ORDER BY `dd`.`Id` DESC LIMIT 2")).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(4, "Test", 1))
+	var first, second []*DummyTable
+	page, err := q.New(dbMock).Select("dd.*").From(dummyTable, "dd").
+		Keyset("", 1, q.KeysetColumn{Column: "dd.Id", Desc: true}).
+		SkipCount(true).
+		FindPage(context.Background(), &first)
+	assert.NoError(t, err)
+
+	//When
+	next, err := q.New(dbMock).Select("dd.*").From(dummyTable, "dd").
+		Keyset(page.Next, 1, q.KeysetColumn{Column: "dd.Id", Desc: true}).
+		SkipCount(true).
+		FindPage(context.Background(), &second)
+
+	//Then
+	assert.NoError(t, err)
+	assert.Len(t, second, 1)
+	assert.Empty(t, next.Next)
+	assert.NotEmpty(t, next.Prev)
+	if err := sqlMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestQuery_Find_Criteria(t *testing.T) {
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+	var nilTime *time.Time
+
+	cases := []struct {
+		name    string
+		fnMocks func(sqlMock sqlmock.Sqlmock)
+		query   func(db *gorm.DB) q.Querier
+	}{
+		{
+			name: "Find_WithWhereClause_Comparisons",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd WHERE (`dd`.`Id` > ? AND `dd`.`Id` >= ? AND `dd`.`Id` < ? AND `dd`.`Id` <= ?)")).
+					WithArgs(1, 2, 10, 9).
+					WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(2, "Test", 1))
+			},
+			query: func(db *gorm.DB) q.Querier {
+				return q.New(db).
+					Select("dd.*").
+					From(dummyTable, "dd").
+					Where(c.And(c.Gt("dd.Id", 1), c.Gte("dd.Id", 2), c.Lt("dd.Id", 10), c.Lte("dd.Id", 9)))
+			},
+		},
+		{
+			name: "Find_WithWhereClause_IfPresent_SkipsNil",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd WHERE `dd`.`Id` >= ?")).
+					WithArgs(2).
+					WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(2, "Test", 1))
+			},
+			query: func(db *gorm.DB) q.Querier {
+				id := 2
+				return q.New(db).
+					Select("dd.*").
+					From(dummyTable, "dd").
+					Where(c.GtIfPresent("dd.Id", nil), c.GteIfPresent("dd.Id", &id), c.LtIfPresent("dd.Created", nilTime))
+			},
+		},
+		{
+			name: "Find_WithWhereClause_Between",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd WHERE `dd`.`Created` BETWEEN ? AND ?")).
+					WithArgs(from, to).
+					WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(1, "Test", 1))
+			},
+			query: func(db *gorm.DB) q.Querier {
+				return q.New(db).
+					Select("dd.*").
+					From(dummyTable, "dd").
+					Where(c.BetweenIfPresent("dd.Created", &from, &to))
+			},
+		},
+		{
+			name: "Find_WithWhereClause_BetweenIfPresent_OpenRange",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd WHERE `dd`.`Created` >= ?")).
+					WithArgs(from).
+					WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(1, "Test", 1))
+			},
+			query: func(db *gorm.DB) q.Querier {
+				return q.New(db).
+					Select("dd.*").
+					From(dummyTable, "dd").
+					Where(c.BetweenIfPresent("dd.Created", &from, nilTime))
+			},
+		},
+		{
+			name: "Find_WithWhereClause_Overlaps",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd WHERE (`dd`.`StartDate` <= ? AND `dd`.`EndDate` >= ?)")).
+					WithArgs(to, from).
+					WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(1, "Test", 1))
+			},
+			query: func(db *gorm.DB) q.Querier {
+				return q.New(db).
+					Select("dd.*").
+					From(dummyTable, "dd").
+					Where(c.Overlaps("dd.StartDate", "dd.EndDate", from, to))
+			},
+		},
+		{
+			name: "Find_WithWhereClause_Exists_Subquery",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd WHERE EXISTS (SELECT 1 FROM one_to AS ot WHERE ot.Id = dd.OneToId AND `ot`.`Status` = ?)")).
+					WithArgs("Active").
+					WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(1, "Test", 1))
+			},
+			query: func(db *gorm.DB) q.Querier {
+				sub := q.New(db).
+					Select("1").
+					From(oneToTable, "ot").
+					Where(clause.Expr{SQL: "ot.Id = dd.OneToId"}, c.Eq("ot.Status", "Active"))
+				return q.New(db).
+					Select("dd.*").
+					From(dummyTable, "dd").
+					Where(c.Exists(sub))
+			},
+		},
+		{
+			name: "Find_WithWhereClause_InSub",
+			fnMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd WHERE `dd`.`Name` = ? AND `dd`.`OneToId` IN (SELECT ot.Id FROM one_to AS ot WHERE `ot`.`Status` = ?)")).
+					WithArgs("Test", "Active").
+					WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(1, "Test", 1))
+			},
+			query: func(db *gorm.DB) q.Querier {
+				sub := q.New(db).
+					Select("ot.Id").
+					From(oneToTable, "ot").
+					Where(c.Eq("ot.Status", "Active"))
+				return q.New(db).
+					Select("dd.*").
+					From(dummyTable, "dd").
+					Where(c.Eq("dd.Name", "Test"), c.InSub("dd.OneToId", sub))
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			dbMock, sqlMock := dbmocks.NewSqlMock()
+			tt.fnMocks(sqlMock)
+			var results []*DummyTable
+
+			//When
+			err := tt.query(dbMock).Find(context.Background(), &results)
+
+			//Then
+			assert.NoError(t, err)
+			if err := sqlMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+type SoftDeleteTable struct {
+	*entity.Base
+	Name string `gorm:"column:Name"`
+	entity.SoftDelete
+}
+
+func (SoftDeleteTable) TableName() string { return "soft" }
+
+func TestQuery_Find_SoftDelete(t *testing.T) {
+	cases := []struct {
+		name    string
+		wantSQL string
+		query   func(db *gorm.DB) q.Querier
+	}{
+		{
+			name:    "Find_ExcludesDeleted",
+			wantSQL: "SELECT dd.* FROM soft AS dd WHERE `dd`.`Name` = ? AND `dd`.`DeletedDatim` IS NULL",
+			query: func(db *gorm.DB) q.Querier {
+				return q.New(db).Select("dd.*").From("soft", "dd").Where(c.Eq("dd.Name", "Test"))
+			},
+		},
+		{
+			name:    "Find_WithDeleted",
+			wantSQL: "SELECT dd.* FROM soft AS dd WHERE `dd`.`Name` = ?",
+			query: func(db *gorm.DB) q.Querier {
+				return q.New(db).Select("dd.*").From("soft", "dd").Where(c.Eq("dd.Name", "Test")).WithDeleted()
+			},
+		},
+		{
+			name:    "Find_OnlyDeleted",
+			wantSQL: "SELECT dd.* FROM soft AS dd WHERE `dd`.`Name` = ? AND `dd`.`DeletedDatim` IS NOT NULL",
+			query: func(db *gorm.DB) q.Querier {
+				return q.New(db).Select("dd.*").From("soft", "dd").Where(c.Eq("dd.Name", "Test")).OnlyDeleted()
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			dbMock, sqlMock := dbmocks.NewSqlMock()
+			sqlMock.ExpectQuery(regexp.QuoteMeta(tt.wantSQL) + "$").
+				WithArgs("Test").
+				WillReturnRows(sqlmock.NewRows([]string{"Id", "Name"}).AddRow(1, "Test"))
+			var results []*SoftDeleteTable
+
+			//When
+			err := tt.query(dbMock).Find(context.Background(), &results)
+
+			//Then
+			assert.NoError(t, err)
+			if err := sqlMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestQuery_Each(t *testing.T) {
+	//Given
+	dbMock, sqlMock := dbmocks.NewSqlMock()
+	sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd WHERE `dd`.`Name` = ? ORDER BY `dd`.`Id` LIMIT 3")).
+		WithArgs("Test").
+		WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(1, "Test", 1).AddRow(2, "Test", 1).AddRow(3, "Test", 1))
+	sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd WHERE `dd`.`Name` = ? AND `dd`.`Id` > ? ORDER BY `dd`.`Id` LIMIT 3")).
+		WithArgs("Test", 2).
+		WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(3, "Test", 1))
+	var (
+		results []*DummyTable
+		batches [][]uint
+	)
+
+	//When
+	err := q.New(dbMock).
+		Select("dd.*").
+		From(dummyTable, "dd").
+		Where(c.Eq("dd.Name", "Test")).
+		Each(context.Background(), 2, &results, func() error {
+			var ids []uint
+			for _, r := range results {
+				ids = append(ids, r.ID)
+			}
+			batches = append(batches, ids)
+			return nil
+		})
+
+	//Then
+	assert.NoError(t, err)
+	assert.Equal(t, [][]uint{{1, 2}, {3}}, batches)
+	if err := sqlMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestQuery_Each_ContextCanceled(t *testing.T) {
+	//Given
+	dbMock, sqlMock := dbmocks.NewSqlMock()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var results []*DummyTable
+
+	//When
+	err := q.New(dbMock).From(dummyTable, "dd").Each(ctx, 2, &results, func() error { return nil })
+
+	//Then
+	assert.ErrorIs(t, err, context.Canceled)
+	if err := sqlMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestQuery_Iterate(t *testing.T) {
+	//Given
+	dbMock, sqlMock := dbmocks.NewSqlMock()
+	sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd ORDER BY `dd`.`Id` LIMIT 3")).
+		WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(1, "Test", 1).AddRow(2, "Test", 1).AddRow(3, "Test", 1))
+	sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd WHERE `dd`.`Id` > ? ORDER BY `dd`.`Id` LIMIT 3")).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(3, "Test", 1))
+	it := q.New(dbMock).Select("dd.*").From(dummyTable, "dd").Iterate(context.Background(), 2)
+
+	//When
+	var (
+		row DummyTable
+		ids []uint
+	)
+	for it.Next(&row) {
+		ids = append(ids, row.ID)
+	}
+
+	//Then
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []uint{1, 2, 3}, ids)
+	if err := sqlMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestQuery_Iterate_Reuse(t *testing.T) {
+	//Given
+	dbMock, sqlMock := dbmocks.NewSqlMock()
+	sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd ORDER BY `dd`.`Id` LIMIT 3")).
+		WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(1, "Test", 1))
+	sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT dd.* FROM dummy AS dd ORDER BY `dd`.`Id` LIMIT 6")).
+		WillReturnRows(sqlmock.NewRows(allSelectDummyCols).AddRow(1, "Test", 1))
+	query := q.New(dbMock).Select("dd.*").From(dummyTable, "dd").
+		Keyset("", 5, q.KeysetColumn{Column: "dd.Id"}).SkipCount(true)
+	it := query.Iterate(context.Background(), 2)
+	var row DummyTable
+	for it.Next(&row) {
+	}
+	assert.NoError(t, it.Err())
+
+	//When
+	var rows []*DummyTable
+	_, err := query.FindPage(context.Background(), &rows)
+
+	//Then
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	if err := sqlMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
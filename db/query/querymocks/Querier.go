@@ -5,6 +5,7 @@ package querymocks
 import (
 	context "context"
 
+	query "github.com/mauricetjmurphy/ms-common/db/query"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -13,6 +14,20 @@ type Querier struct {
 	mock.Mock
 }
 
+// Each provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *Querier) Each(_a0 context.Context, _a1 int, _a2 interface{}, _a3 func() error) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, interface{}, func() error) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Find provides a mock function with given fields: _a0, _a1
 func (_m *Querier) Find(_a0 context.Context, _a1 interface{}) error {
 	ret := _m.Called(_a0, _a1)
@@ -47,3 +62,26 @@ func (_m *Querier) FindAll(_a0 context.Context, _a1 interface{}) (int64, error)
 
 	return r0, r1
 }
+
+// FindPage provides a mock function with given fields: _a0, _a1
+func (_m *Querier) FindPage(_a0 context.Context, _a1 interface{}) (*query.KeysetPage, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *query.KeysetPage
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) *query.KeysetPage); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*query.KeysetPage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
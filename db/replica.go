@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+type forcePrimaryKey struct{}
+
+// ForcePrimary returns a copy of ctx routing the reads to the primary, e.g. to read back
+// the rows written by the same request before they reach the replicas.
+func ForcePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcePrimaryKey{}, true)
+}
+
+// IsForcePrimary reports whether the reads on given context are routed to the primary.
+func IsForcePrimary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	force, _ := ctx.Value(forcePrimaryKey{}).(bool)
+	return force
+}
+
+// useReplicas registers the read replicas on tx. Queries are routed to the replicas,
+// while writes, transactions and ForcePrimary reads stay on the primary.
+func useReplicas(tx *gorm.DB, replicas []gorm.Dialector) (*dbresolver.DBResolver, error) {
+	resolver := dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+	})
+	if err := tx.Use(resolver); err != nil {
+		return nil, err
+	}
+
+	// the resolver runs first on every statement, so the override switches the
+	// connection back to the primary right before the statement is executed.
+	cb := tx.Callback()
+	if err := cb.Query().Before("gorm:query").Register("db:force_primary", forcePrimary); err != nil {
+		return nil, err
+	}
+	if err := cb.Row().Before("gorm:row").Register("db:force_primary", forcePrimary); err != nil {
+		return nil, err
+	}
+	if err := cb.Raw().Before("gorm:raw").Register("db:force_primary", forcePrimary); err != nil {
+		return nil, err
+	}
+	return resolver, nil
+}
+
+func forcePrimary(tx *gorm.DB) {
+	if IsForcePrimary(tx.Statement.Context) {
+		dbresolver.Write.ModifyStatement(tx.Statement)
+	}
+}
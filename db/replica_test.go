@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type replicaDummy struct {
+	ID   uint   `gorm:"column:Id;primaryKey"`
+	Name string `gorm:"column:Name"`
+}
+
+func (replicaDummy) TableName() string { return "dummy" }
+
+func newMockDialector(t *testing.T) (gorm.Dialector, sqlmock.Sqlmock) {
+	sqlDB, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	return mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}), sqlMock
+}
+
+func TestDB_Replicas(t *testing.T) {
+	cases := []struct {
+		name           string
+		fnPrimaryMocks func(sqlMock sqlmock.Sqlmock)
+		fnReplicaMocks func(sqlMock sqlmock.Sqlmock)
+		run            func(db DB) error
+	}{
+		{
+			name: "Find_RoutedToReplica",
+			fnPrimaryMocks: func(sqlMock sqlmock.Sqlmock) {
+			},
+			fnReplicaMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery("SELECT \\* FROM `dummy`").
+					WillReturnRows(sqlmock.NewRows([]string{"Id", "Name"}).AddRow(1, "Test"))
+			},
+			run: func(db DB) error {
+				var results []*replicaDummy
+				return db.Query().Find(context.Background(), &results)
+			},
+		},
+		{
+			name: "Find_ForcePrimary_RoutedToPrimary",
+			fnPrimaryMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery("SELECT \\* FROM `dummy`").
+					WillReturnRows(sqlmock.NewRows([]string{"Id", "Name"}).AddRow(1, "Test"))
+			},
+			fnReplicaMocks: func(sqlMock sqlmock.Sqlmock) {
+			},
+			run: func(db DB) error {
+				var results []*replicaDummy
+				return db.Query().Find(ForcePrimary(context.Background()), &results)
+			},
+		},
+		{
+			name: "Save_RoutedToPrimary",
+			fnPrimaryMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec("INSERT INTO `dummy`").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				sqlMock.ExpectCommit()
+			},
+			fnReplicaMocks: func(sqlMock sqlmock.Sqlmock) {
+			},
+			run: func(db DB) error {
+				return db.Save(context.Background(), &replicaDummy{Name: "Test"})
+			},
+		},
+		{
+			name: "Transaction_RoutedToPrimary",
+			fnPrimaryMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery("SELECT \\* FROM `dummy`").
+					WillReturnRows(sqlmock.NewRows([]string{"Id", "Name"}).AddRow(1, "Test"))
+				sqlMock.ExpectCommit()
+			},
+			fnReplicaMocks: func(sqlMock sqlmock.Sqlmock) {
+			},
+			run: func(db DB) error {
+				return db.Transaction(context.Background(), func(tx DB) error {
+					var results []*replicaDummy
+					return tx.Query().Find(context.Background(), &results)
+				})
+			},
+		},
+		{
+			name: "Ping_SchemaVersion_RoutedToPrimary",
+			fnPrimaryMocks: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery("SELECT DATABASE()").
+					WillReturnRows(sqlmock.NewRows([]string{"DATABASE()"}).AddRow("rights"))
+				sqlMock.ExpectQuery("SELECT count\\(\\*\\) FROM information_schema.tables").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				sqlMock.ExpectQuery("SELECT version, dirty FROM `schema_migrations`").
+					WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(3, false))
+			},
+			fnReplicaMocks: func(sqlMock sqlmock.Sqlmock) {
+			},
+			run: func(db DB) error {
+				_, err := db.Ping(context.Background())
+				return err
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			primary, primaryMock := newMockDialector(t)
+			replica, replicaMock := newMockDialector(t)
+			tx, err := gorm.Open(primary, &gorm.Config{Logger: logger.Discard})
+			require.NoError(t, err)
+			resolver, err := useReplicas(tx, []gorm.Dialector{replica})
+			require.NoError(t, err)
+			tt.fnPrimaryMocks(primaryMock)
+			tt.fnReplicaMocks(replicaMock)
+
+			//When
+			err = tt.run(&dbImpl{DB: tx, resolver: resolver})
+
+			//Then
+			assert.NoError(t, err)
+			if err := primaryMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled primary expectations: %s", err)
+			}
+			if err := replicaMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled replica expectations: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+	"time"
+
+	"github.com/mauricetjmurphy/ms-common/clients/aws/secrets"
+	"github.com/mauricetjmurphy/ms-common/logx"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultSecretRefreshInterval is the default interval to check the secret for a new version.
+	DefaultSecretRefreshInterval = 5 * time.Minute
+
+	// defaultMaxIdleConns mirrors the database/sql default of idle connections.
+	defaultMaxIdleConns = 2
+)
+
+// secretLoader loads the connection parameters and the version of the secret they were read from.
+type secretLoader func(ctx context.Context) (*dsnConf, string, error)
+
+// credentials holds the secret backed connection parameters. The parameters are re-fetched when
+// the database rejects them or when the secret version changes, and the idle connections opened
+// with the previous parameters are dropped so the pools are rebuilt with the rotated ones.
+type credentials struct {
+	mu      sync.RWMutex
+	dsn     *dsnConf
+	version string
+	load    secretLoader
+	cfg     *Config
+	pools   []*sql.DB
+	stop    chan struct{}
+	once    sync.Once
+	// refreshing is closed when the refresh in flight completes, nil when none is.
+	refreshing chan struct{}
+	// open creates the driver connector for given connection parameters.
+	open func(dsn *dsnConf) (driver.Connector, error)
+}
+
+// newSecretCredentials loads the credentials stored under given secret ID.
+func newSecretCredentials(ctx context.Context, cfg *Config, secretID string) (*credentials, error) {
+	sm, err := secrets.New(ctx, cfg.AWSRegion)
+	if err != nil {
+		return nil, errors.Wrapf(err, "db : failed to create secrets managers instance on region %v", cfg.AWSRegion)
+	}
+	return newCredentials(ctx, cfg, func(ctx context.Context) (*dsnConf, string, error) {
+		secret, err := sm.GetSecret(ctx, secretID)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "db : failed to load GetSecret(%v) on region %v", secretID, cfg.AWSRegion)
+		}
+		return cfg.withSession(&dsnConf{
+			Host:     secret.Host,
+			Port:     secret.Port,
+			Name:     secret.Name,
+			User:     secret.Username,
+			Password: secret.Password,
+		}), secret.VersionID, nil
+	})
+}
+
+func newCredentials(ctx context.Context, cfg *Config, load secretLoader) (*credentials, error) {
+	dsn, version, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &credentials{
+		dsn:     dsn,
+		version: version,
+		load:    load,
+		cfg:     cfg,
+		stop:    make(chan struct{}),
+		open: func(dsn *dsnConf) (driver.Connector, error) {
+			return cfg.Dialect.Connector(cfg.dsn(dsn))
+		},
+	}, nil
+}
+
+// get returns the current connection parameters.
+func (c *credentials) get() *dsnConf {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.dsn
+}
+
+// refresh re-fetches the secret, unless stale parameters have already been replaced
+// since they were read, and drops the idle connections when the secret has rotated.
+// The secret is loaded outside of the lock, so the connections keep reading the current
+// parameters meanwhile, and the concurrent refreshes wait for the one in flight.
+func (c *credentials) refresh(ctx context.Context, stale *dsnConf) error {
+	c.mu.Lock()
+	if stale != nil && stale != c.dsn {
+		c.mu.Unlock()
+		return nil
+	}
+	if inFlight := c.refreshing; inFlight != nil {
+		c.mu.Unlock()
+		select {
+		case <-inFlight:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	done := make(chan struct{})
+	c.refreshing = done
+	c.mu.Unlock()
+
+	dsn, version, err := c.load(ctx)
+
+	c.mu.Lock()
+	c.refreshing = nil
+	close(done)
+	if err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	rotated := version != c.version || dsn.User != c.dsn.User || dsn.Password != c.dsn.Password
+	c.dsn, c.version = dsn, version
+	pools := c.pools
+	c.mu.Unlock()
+
+	if rotated {
+		logx.Infof("db : credentials rotated to secret version %v, rebuilding connection pools", version)
+		for _, pool := range pools {
+			c.drain(pool)
+		}
+	}
+	return nil
+}
+
+// drain closes the idle connections of the pool, so the next calls connect with the current parameters.
+func (c *credentials) drain(pool *sql.DB) {
+	pool.SetMaxIdleConns(-1)
+	if c.cfg.MaxIdleConns > 0 {
+		pool.SetMaxIdleConns(c.cfg.MaxIdleConns)
+	} else {
+		pool.SetMaxIdleConns(defaultMaxIdleConns)
+	}
+}
+
+// dialector opens a connection pool on the credentials, host overrides the secret host for the
+// read replicas sharing the primary credentials.
+func (c *credentials) dialector(host string) gorm.Dialector {
+	conn := &connector{creds: c, host: host}
+	pool := sql.OpenDB(conn)
+	c.mu.Lock()
+	c.pools = append(c.pools, pool)
+	c.mu.Unlock()
+	return c.cfg.Dialect.Dialector(c.cfg.dsn(conn.dsn(c.get())), pool)
+}
+
+// watch checks the secret for a new version on every interval until Close.
+func (c *credentials) watch(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				if err := c.refresh(ctx, nil); err != nil {
+					logx.Errorf("db : failed to refresh the secret credentials: %v", err)
+				}
+				cancel()
+			}
+		}
+	}()
+}
+
+// Close stops watching the secret.
+func (c *credentials) Close() {
+	c.once.Do(func() {
+		close(c.stop)
+	})
+}
+
+// connector opens the connections with the current credentials. A connection rejected for
+// access denied is retried once with the re-fetched credentials.
+type connector struct {
+	creds *credentials
+	host  string
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	dsn := c.creds.get()
+	conn, err := c.connect(ctx, dsn)
+	if err == nil || !c.creds.cfg.Dialect.IsAuthError(err) {
+		return conn, err
+	}
+	if rerr := c.creds.refresh(ctx, dsn); rerr != nil {
+		logx.Errorf("db : failed to refresh the secret credentials: %v", rerr)
+		return nil, err
+	}
+	return c.connect(ctx, c.creds.get())
+}
+
+func (c *connector) Driver() driver.Driver {
+	conn, err := c.creds.open(c.dsn(c.creds.get()))
+	if err != nil {
+		return errDriver{err: err}
+	}
+	return conn.Driver()
+}
+
+// errDriver is the driver of a connector failing to create the dialect connector, reporting the error on Open.
+type errDriver struct {
+	err error
+}
+
+func (d errDriver) Open(string) (driver.Conn, error) {
+	return nil, d.err
+}
+
+// dsn applies the host override on given connection parameters.
+func (c *connector) dsn(dsn *dsnConf) *dsnConf {
+	if c.host != "" {
+		return dsn.WithHost(c.host)
+	}
+	return dsn
+}
+
+func (c *connector) connect(ctx context.Context, dsn *dsnConf) (driver.Conn, error) {
+	conn, err := c.creds.open(c.dsn(dsn))
+	if err != nil {
+		return nil, err
+	}
+	return conn.Connect(ctx)
+}
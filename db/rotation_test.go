@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/mauricetjmurphy/ms-common/db/dialect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeConn struct {
+	driver.Conn
+	password string
+}
+
+type fakeConnector struct {
+	dsn *dsnConf
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	if c.dsn.Password == "rotated" {
+		return &fakeConn{password: c.dsn.Password}, nil
+	}
+	return nil, &mysqldriver.MySQLError{Number: 1045, Message: "Access denied"}
+}
+
+func (c *fakeConnector) Driver() driver.Driver { return mysqldriver.MySQLDriver{} }
+
+// fakeSecret returns a loader serving the given versions of the secret, one per load.
+func fakeSecret(loads *int, versions ...string) secretLoader {
+	return func(ctx context.Context) (*dsnConf, string, error) {
+		version := versions[*loads]
+		if *loads < len(versions)-1 {
+			*loads++
+		}
+		return &dsnConf{User: "user", Password: version}, version, nil
+	}
+}
+
+func TestConnector_Connect(t *testing.T) {
+	cases := []struct {
+		name         string
+		versions     []string
+		wantErr      bool
+		wantPassword string
+	}{
+		{
+			name:         "Connect_CurrentCredentials",
+			versions:     []string{"rotated"},
+			wantPassword: "rotated",
+		},
+		{
+			name:         "Connect_AccessDenied_RetriedWithRotatedCredentials",
+			versions:     []string{"initial", "rotated"},
+			wantPassword: "rotated",
+		},
+		{
+			name:     "Connect_AccessDenied_NotRotated",
+			versions: []string{"initial"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			var loads int
+			creds, err := newCredentials(context.Background(), NewConfigs(), fakeSecret(&loads, tt.versions...))
+			require.NoError(t, err)
+			creds.open = func(dsn *dsnConf) (driver.Connector, error) {
+				return &fakeConnector{dsn: dsn}, nil
+			}
+
+			//When
+			conn, err := (&connector{creds: creds}).Connect(context.Background())
+
+			//Then
+			if tt.wantErr {
+				assert.True(t, dialect.MySQL().IsAuthError(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPassword, conn.(*fakeConn).password)
+		})
+	}
+}
+
+func TestCredentials_Refresh_Stale(t *testing.T) {
+	//Given
+	var loads int
+	creds, err := newCredentials(context.Background(), NewConfigs(), fakeSecret(&loads, "initial", "rotated", "again"))
+	require.NoError(t, err)
+	stale := creds.get()
+	require.NoError(t, creds.refresh(context.Background(), stale))
+
+	//When
+	err = creds.refresh(context.Background(), stale)
+
+	//Then
+	assert.NoError(t, err)
+	assert.Equal(t, "rotated", creds.get().Password)
+	assert.Equal(t, "rotated", creds.version)
+}
+
+func TestCredentials_Refresh_DoesNotBlockGet(t *testing.T) {
+	//Given
+	loading, release := make(chan struct{}), make(chan struct{})
+	var loads int
+	creds, err := newCredentials(context.Background(), NewConfigs(), func(ctx context.Context) (*dsnConf, string, error) {
+		if loads++; loads > 1 {
+			close(loading)
+			<-release
+			return &dsnConf{User: "user", Password: "rotated"}, "rotated", nil
+		}
+		return &dsnConf{User: "user", Password: "initial"}, "initial", nil
+	})
+	require.NoError(t, err)
+	stale := creds.get()
+	refreshed := make(chan error, 2)
+	go func() { refreshed <- creds.refresh(context.Background(), stale) }()
+	<-loading
+
+	//When
+	got := make(chan *dsnConf, 1)
+	go func() { got <- creds.get() }()
+	go func() { refreshed <- creds.refresh(context.Background(), stale) }()
+
+	//Then
+	select {
+	case dsn := <-got:
+		assert.Equal(t, "initial", dsn.Password)
+	case <-time.After(time.Second):
+		t.Fatal("get blocked by the refresh in flight")
+	}
+	close(release)
+	assert.NoError(t, <-refreshed)
+	assert.NoError(t, <-refreshed)
+	assert.Equal(t, 2, loads)
+	assert.Equal(t, "rotated", creds.get().Password)
+}
+
+func TestConnector_Driver_OpenError(t *testing.T) {
+	//Given
+	var loads int
+	creds, err := newCredentials(context.Background(), NewConfigs(), fakeSecret(&loads, "initial"))
+	require.NoError(t, err)
+	errOpen := errors.New("invalid dsn")
+	creds.open = func(*dsnConf) (driver.Connector, error) {
+		return nil, errOpen
+	}
+
+	//When
+	drv := (&connector{creds: creds}).Driver()
+
+	//Then
+	require.NotNil(t, drv)
+	_, err = drv.Open("")
+	assert.Equal(t, errOpen, err)
+}
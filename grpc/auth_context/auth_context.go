@@ -5,12 +5,13 @@ import (
 )
 
 type (
-	contextKey struct{}
+	ssoContextKey    struct{}
+	userIDContextKey struct{}
 )
 
 var (
-	contextSSOKey    = contextKey{}
-	contextUserIDKey = contextKey{}
+	contextSSOKey    = ssoContextKey{}
+	contextUserIDKey = userIDContextKey{}
 )
 
 func WithSso(ctx context.Context, sso string) context.Context {
@@ -0,0 +1,57 @@
+package auth_context
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthContext(t *testing.T) {
+	cases := []struct {
+		name       string
+		ctx        context.Context
+		wantSsoID  string
+		wantUserID uint
+	}{
+		{
+			name:      "SsoOnly",
+			ctx:       WithSso(context.Background(), "206000001"),
+			wantSsoID: "206000001",
+		},
+		{
+			name:       "UserIdOnly",
+			ctx:        WithUserId(context.Background(), 7),
+			wantUserID: 7,
+		},
+		{
+			name:       "UserIdThenSso",
+			ctx:        WithSso(WithUserId(context.Background(), 7), "206000001"),
+			wantSsoID:  "206000001",
+			wantUserID: 7,
+		},
+		{
+			name:       "SsoThenUserId",
+			ctx:        WithUserId(WithSso(context.Background(), "206000001"), 7),
+			wantSsoID:  "206000001",
+			wantUserID: 7,
+		},
+		{
+			name: "Empty",
+			ctx:  context.Background(),
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			ctx := tt.ctx
+
+			//When
+			ssoID, userID := SsoID(ctx), UserId(ctx)
+
+			//Then
+			assert.Equal(t, tt.wantSsoID, ssoID)
+			assert.Equal(t, tt.wantUserID, userID)
+		})
+	}
+}
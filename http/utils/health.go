@@ -6,6 +6,7 @@ import (
 	"github.com/mauricetjmurphy/ms-common/logx"
 )
 
+// HealthCheck answers a static OK status, see server/health for the probes backed by checks.
 func HealthCheck(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Add("Cache-Control", "no-cache, no-store, must-revalidate")
 	w.Header().Set(ContentType, ContentTypeJSON)
@@ -105,7 +105,7 @@ func (l *defaultLogger) Panic(ctx context.Context, msg interface{}, fields ...Fi
 }
 
 func (l *defaultLogger) WithContext(ctx context.Context) *logrus.Entry {
-	return logrus.WithContext(ctx)
+	return logrus.WithContext(ctx).WithFields(logrus.Fields(fieldsFromContext(ctx)))
 }
 
 func (l *defaultLogger) entryFromContext(ctx context.Context, msg interface{}, fields ...Fields) *logrus.Entry {
@@ -115,13 +115,8 @@ func (l *defaultLogger) entryFromContext(ctx context.Context, msg interface{}, f
 		merged["error"] = err
 	}
 	// Extract fields from context.
-	if ctx != nil {
-		cf, ok := ctx.Value(contextFieldsKey).(Fields)
-		if ok {
-			for k, v := range cf {
-				merged[k] = v
-			}
-		}
+	for k, v := range fieldsFromContext(ctx) {
+		merged[k] = v
 	}
 	// Extract fields from optional passed in fields arg(s).
 	for _, f := range fields {
@@ -131,3 +126,26 @@ func (l *defaultLogger) entryFromContext(ctx context.Context, msg interface{}, f
 	}
 	return logrus.WithFields(logrus.Fields(merged))
 }
+
+// ContextWithFields returns a copy of ctx carrying the given fields merged with any fields already
+// stored on ctx. The fields are attached to every entry logged with the returned context.
+func ContextWithFields(ctx context.Context, fields Fields) context.Context {
+	merged := Fields{}
+	for k, v := range fieldsFromContext(ctx) {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, contextFieldsKey, merged)
+}
+
+func fieldsFromContext(ctx context.Context) Fields {
+	if ctx == nil {
+		return nil
+	}
+	if cf, ok := ctx.Value(contextFieldsKey).(Fields); ok {
+		return cf
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+// Package checkers provides the health checks of the ms-common clients, kept apart from the health
+// package so the servers only link the clients they use.
+package checkers
+
+import (
+	"context"
+
+	"github.com/mauricetjmurphy/ms-common/clients/aws/sqs"
+	httpclient "github.com/mauricetjmurphy/ms-common/clients/http"
+	"github.com/mauricetjmurphy/ms-common/db"
+	"github.com/mauricetjmurphy/ms-common/server/health"
+	"github.com/pkg/errors"
+)
+
+// DB checks the database is reachable and its schema is not left dirty by a failed migration.
+func DB(conn db.DB) health.Checker {
+	return health.CheckerFunc(func(ctx context.Context) error {
+		dbHealth, err := conn.Ping(ctx)
+		if err != nil {
+			return err
+		}
+		if dbHealth.MigrationDirty {
+			return errors.Errorf("checkers : schema version %v is dirty", dbHealth.MigrationVersion)
+		}
+		return nil
+	})
+}
+
+// SQS checks the queue is reachable.
+func SQS(client sqs.Client) health.Checker {
+	return health.CheckerFunc(client.Ping)
+}
+
+// HTTP checks the downstream endpoint answers the GET request on path with a success status.
+func HTTP(client *httpclient.JSONClient, path string) health.Checker {
+	return health.CheckerFunc(func(ctx context.Context) error {
+		_, err := client.Get(ctx, path, nil, nil)
+		return err
+	})
+}
@@ -0,0 +1,262 @@
+// Package health provides the readiness checks of a service, reported by the standard gRPC
+// health service grpc.health.v1.Health and by the /healthz and /readyz HTTP probes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mauricetjmurphy/ms-common/logx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultTimeout is the timeout of a check registered without one.
+	DefaultTimeout = 2 * time.Second
+
+	// LivenessPath and ReadinessPath are the HTTP probes paths.
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+
+	// CheckMethod is the gRPC health check method, e.g. to exclude it from the authentication interceptors.
+	CheckMethod = "/grpc.health.v1.Health/Check"
+
+	// watchInterval is the interval the watched serving status is checked.
+	watchInterval = 5 * time.Second
+)
+
+// Status is the status of the service and its checks.
+type Status string
+
+const (
+	StatusOK           Status = "OK"
+	StatusDown         Status = "DOWN"
+	StatusShuttingDown Status = "SHUTTING_DOWN"
+)
+
+// Checker checks one dependency of the service is available.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to the Checker.
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Report is the readiness report of the service.
+type Report struct {
+	Status Status                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the result of one check.
+type CheckResult struct {
+	Status   Status `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type check struct {
+	name    string
+	checker Checker
+	timeout time.Duration
+}
+
+// Health runs the readiness checks of the service. The service is ready when all the checks pass,
+// and is no longer ready once Shutdown is called.
+type Health struct {
+	mu       sync.RWMutex
+	checks   []check
+	stopping bool
+	// stopped is closed by Shutdown.
+	stopped chan struct{}
+}
+
+// New creates the Health with no check, always ready until Shutdown.
+func New() *Health {
+	return &Health{stopped: make(chan struct{})}
+}
+
+// Register adds the named check, run with given timeout, or DefaultTimeout when not positive.
+func (h *Health) Register(name string, checker Checker, timeout time.Duration) *Health {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, check{name: name, checker: checker, timeout: timeout})
+	return h
+}
+
+// Shutdown reports the service as no longer ready.
+func (h *Health) Shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.stopping {
+		h.stopping = true
+		close(h.stopped)
+	}
+}
+
+// Check runs the checks concurrently and reports the service readiness.
+func (h *Health) Check(ctx context.Context) *Report {
+	h.mu.RLock()
+	checks, stopping := h.checks, h.stopping
+	h.mu.RUnlock()
+	if stopping {
+		return &Report{Status: StatusShuttingDown}
+	}
+
+	report := &Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checks))}
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func run(ctx context.Context, c check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	begin := time.Now()
+	err := c.checker.Check(ctx)
+	result := CheckResult{Status: StatusOK, Duration: time.Since(begin).String()}
+	if err != nil {
+		logx.Warnf("health : check %v failed: %v", c.name, err)
+		result.Status, result.Error = StatusDown, err.Error()
+	}
+	return result
+}
+
+// Liveness serves the liveness probe, the service is alive as long as it answers.
+func (h *Health) Liveness(w http.ResponseWriter, _ *http.Request) {
+	write(w, http.StatusOK, &Report{Status: StatusOK})
+}
+
+// Readiness serves the readiness probe, answering 503 Service Unavailable when the service is not ready.
+func (h *Health) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := h.Check(r.Context())
+	code := http.StatusOK
+	if report.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	write(w, code, report)
+}
+
+func write(w http.ResponseWriter, code int, report *Report) {
+	w.Header().Add("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logx.Errorf("health : failed to write the report %v", err)
+	}
+}
+
+// Handler serves the liveness and readiness probes, and the other requests with next.
+func (h *Health) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case LivenessPath:
+			h.Liveness(w, r)
+		case ReadinessPath:
+			h.Readiness(w, r)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// RegisterGRPC registers the grpc.health.v1.Health service on the gRPC server. All the services
+// of the server share its readiness, the empty service name being the server itself.
+func (h *Health) RegisterGRPC(s *grpc.Server) {
+	healthpb.RegisterHealthServer(s, &grpcServer{health: h, server: s})
+}
+
+type grpcServer struct {
+	healthpb.UnimplementedHealthServer
+	health *Health
+	server *grpc.Server
+}
+
+func (s *grpcServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if !s.known(req.GetService()) {
+		return nil, status.Errorf(codes.NotFound, "health : unknown service %v", req.GetService())
+	}
+	return &healthpb.HealthCheckResponse{Status: s.status(ctx)}, nil
+}
+
+// Watch streams the serving status on every change until the stream is canceled, or until the
+// NOT_SERVING status is sent once the health is shut down, not to hold the graceful stop of the
+// server. An unknown service is reported SERVICE_UNKNOWN.
+func (s *grpcServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	if !s.known(req.GetService()) {
+		if err := stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVICE_UNKNOWN}); err != nil {
+			return err
+		}
+		select {
+		case <-stream.Context().Done():
+			return status.Error(codes.Canceled, "health : watch canceled")
+		case <-s.health.stopped:
+			return nil
+		}
+	}
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	for {
+		if current := s.status(stream.Context()); current != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: current}); err != nil {
+				return err
+			}
+			last = current
+		}
+		select {
+		case <-stream.Context().Done():
+			return status.Error(codes.Canceled, "health : watch canceled")
+		case <-s.health.stopped:
+			if last == healthpb.HealthCheckResponse_NOT_SERVING {
+				return nil
+			}
+			return stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING})
+		case <-ticker.C:
+		}
+	}
+}
+
+// known reports whether the service is served by the gRPC server, the empty name being the server itself.
+func (s *grpcServer) known(service string) bool {
+	if service == "" || s.server == nil {
+		return service == ""
+	}
+	_, ok := s.server.GetServiceInfo()[service]
+	return ok
+}
+
+func (s *grpcServer) status(ctx context.Context) healthpb.HealthCheckResponse_ServingStatus {
+	if s.health.Check(ctx).Status != StatusOK {
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	return healthpb.HealthCheckResponse_SERVING
+}
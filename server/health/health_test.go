@@ -0,0 +1,218 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestHealth_Check(t *testing.T) {
+	pass := CheckerFunc(func(context.Context) error { return nil })
+	fail := CheckerFunc(func(context.Context) error { return errors.New("connection refused") })
+	hang := CheckerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	cases := []struct {
+		name       string
+		health     func() *Health
+		wantStatus Status
+		wantChecks map[string]Status
+		wantCode   int
+		wantGRPC   healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{
+			name:       "Check_NoChecks_OK",
+			health:     New,
+			wantStatus: StatusOK,
+			wantChecks: map[string]Status{},
+			wantCode:   http.StatusOK,
+			wantGRPC:   healthpb.HealthCheckResponse_SERVING,
+		},
+		{
+			name: "Check_AllPass_OK",
+			health: func() *Health {
+				return New().Register("db", pass, 0).Register("sqs", pass, 0)
+			},
+			wantStatus: StatusOK,
+			wantChecks: map[string]Status{"db": StatusOK, "sqs": StatusOK},
+			wantCode:   http.StatusOK,
+			wantGRPC:   healthpb.HealthCheckResponse_SERVING,
+		},
+		{
+			name: "Check_OneFails_Down",
+			health: func() *Health {
+				return New().Register("db", pass, 0).Register("sqs", fail, 0)
+			},
+			wantStatus: StatusDown,
+			wantChecks: map[string]Status{"db": StatusOK, "sqs": StatusDown},
+			wantCode:   http.StatusServiceUnavailable,
+			wantGRPC:   healthpb.HealthCheckResponse_NOT_SERVING,
+		},
+		{
+			name: "Check_Timeout_Down",
+			health: func() *Health {
+				return New().Register("rights", hang, 10*time.Millisecond)
+			},
+			wantStatus: StatusDown,
+			wantChecks: map[string]Status{"rights": StatusDown},
+			wantCode:   http.StatusServiceUnavailable,
+			wantGRPC:   healthpb.HealthCheckResponse_NOT_SERVING,
+		},
+		{
+			name: "Check_Shutdown_ShuttingDown",
+			health: func() *Health {
+				h := New().Register("db", pass, 0)
+				h.Shutdown()
+				return h
+			},
+			wantStatus: StatusShuttingDown,
+			wantCode:   http.StatusServiceUnavailable,
+			wantGRPC:   healthpb.HealthCheckResponse_NOT_SERVING,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			h := tt.health()
+			handler := h.Handler(http.NotFoundHandler())
+
+			//When
+			report := h.Check(context.Background())
+			readiness := httptest.NewRecorder()
+			handler.ServeHTTP(readiness, httptest.NewRequest(http.MethodGet, ReadinessPath, nil))
+			liveness := httptest.NewRecorder()
+			handler.ServeHTTP(liveness, httptest.NewRequest(http.MethodGet, LivenessPath, nil))
+			grpcResp, err := (&grpcServer{health: h}).Check(context.Background(), &healthpb.HealthCheckRequest{})
+
+			//Then
+			assert.Equal(t, tt.wantStatus, report.Status)
+			checks := make(map[string]Status)
+			for name, result := range report.Checks {
+				checks[name] = result.Status
+			}
+			if tt.wantChecks != nil {
+				assert.Equal(t, tt.wantChecks, checks)
+			}
+			assert.Equal(t, tt.wantCode, readiness.Code)
+			assert.Equal(t, http.StatusOK, liveness.Code)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantGRPC, grpcResp.Status)
+		})
+	}
+}
+
+// newHealthClient serves the health service of h on a local gRPC server.
+func newHealthClient(t *testing.T, h *Health) healthpb.HealthClient {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	h.RegisterGRPC(srv)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestGRPCServer_Check_Service(t *testing.T) {
+	cases := []struct {
+		name     string
+		service  string
+		wantCode codes.Code
+	}{
+		{
+			name: "Check_Server",
+		},
+		{
+			name:    "Check_RegisteredService",
+			service: healthpb.Health_ServiceDesc.ServiceName,
+		},
+		{
+			name:     "Check_UnknownService_NotFound",
+			service:  "rights.v1.Unknown",
+			wantCode: codes.NotFound,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			client := newHealthClient(t, New())
+
+			//When
+			resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: tt.service})
+
+			//Then
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			if tt.wantCode == codes.OK {
+				assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+			}
+		})
+	}
+}
+
+func TestGRPCServer_Watch(t *testing.T) {
+	cases := []struct {
+		name       string
+		service    string
+		wantStatus []healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{
+			name: "Watch_Shutdown_NotServingThenEnds",
+			wantStatus: []healthpb.HealthCheckResponse_ServingStatus{
+				healthpb.HealthCheckResponse_SERVING,
+				healthpb.HealthCheckResponse_NOT_SERVING,
+			},
+		},
+		{
+			name:    "Watch_UnknownService_ServiceUnknown",
+			service: "rights.v1.Unknown",
+			wantStatus: []healthpb.HealthCheckResponse_ServingStatus{
+				healthpb.HealthCheckResponse_SERVICE_UNKNOWN,
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			h := New()
+			client := newHealthClient(t, h)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: tt.service})
+			require.NoError(t, err)
+			first, err := stream.Recv()
+			require.NoError(t, err)
+
+			//When
+			h.Shutdown()
+
+			//Then
+			got := []healthpb.HealthCheckResponse_ServingStatus{first.Status}
+			for {
+				resp, err := stream.Recv()
+				if err != nil {
+					assert.Equal(t, io.EOF, err)
+					break
+				}
+				got = append(got, resp.Status)
+			}
+			assert.Equal(t, tt.wantStatus, got)
+		})
+	}
+}
@@ -1,12 +1,22 @@
 package server
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/mauricetjmurphy/ms-common/server/health"
 	"google.golang.org/grpc"
 )
 
+// DefaultGracePeriod is the default time given to the shutdown to drain the in-flight requests and run the hooks.
+const DefaultGracePeriod = 20 * time.Second
+
+// Hook is a function run on shutdown, e.g. to stop the SQS workers or flush the publishers.
+// The context is done at the end of the shutdown grace period.
+type Hook func(ctx context.Context) error
+
 // Option is an optional setting applied to the Server.
 type Option func(*serverOpts)
 
@@ -20,13 +30,30 @@ type serverOpts struct {
 	HTTPMiddlewares []func(http.Handler) http.Handler
 
 	Logger Logger
+
+	GracePeriod time.Duration
+	PreStop     []Hook
+	PostStop    []Hook
+
+	HealthChecks []HealthCheck
+
+	TLS *TLSConfig
+}
+
+// HealthCheck is one readiness check of the server.
+type HealthCheck struct {
+	Name    string
+	Checker health.Checker
+	// Timeout of the check, defaults to health.DefaultTimeout.
+	Timeout time.Duration
 }
 
 func defaultServerOpts(addr string) *serverOpts {
 	return &serverOpts{
-		GRPCAddr: addr,
-		HTTPAddr: addr,
-		Logger:   &defaultLogger{},
+		GRPCAddr:    addr,
+		HTTPAddr:    addr,
+		Logger:      &defaultLogger{},
+		GracePeriod: DefaultGracePeriod,
 	}
 }
 
@@ -70,3 +97,41 @@ func WithLoggerOpts(logger Logger) Option {
 		o.Logger = logger
 	}
 }
+
+// WithGracePeriod sets the time given to the shutdown to drain the in-flight requests and run the hooks.
+func WithGracePeriod(d time.Duration) Option {
+	return func(o *serverOpts) {
+		o.GracePeriod = d
+	}
+}
+
+// WithPreStop adds the hooks run on shutdown before the servers stop accepting requests,
+// e.g. to stop consuming the SQS queues.
+func WithPreStop(hooks ...Hook) Option {
+	return func(o *serverOpts) {
+		o.PreStop = append(o.PreStop, hooks...)
+	}
+}
+
+// WithPostStop adds the hooks run on shutdown once the in-flight requests are drained,
+// e.g. to flush the publishers and close the DB.
+func WithPostStop(hooks ...Hook) Option {
+	return func(o *serverOpts) {
+		o.PostStop = append(o.PostStop, hooks...)
+	}
+}
+
+// WithHealthCheck adds the named readiness check, e.g. checkers.DB(conn), run with given timeout.
+func WithHealthCheck(name string, checker health.Checker, timeout time.Duration) Option {
+	return func(o *serverOpts) {
+		o.HealthChecks = append(o.HealthChecks, HealthCheck{Name: name, Checker: checker, Timeout: timeout})
+	}
+}
+
+// WithTLS serves gRPC and HTTP over TLS with the certificates of cfg, reloaded when their files change.
+// Setting cfg.ClientCAFile requires and verifies the client certificates, see ClientIdentity.
+func WithTLS(cfg TLSConfig) Option {
+	return func(o *serverOpts) {
+		o.TLS = &cfg
+	}
+}
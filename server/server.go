@@ -1,24 +1,38 @@
 package server
 
 import (
+	"context"
 	nett "net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 
 	http "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/mauricetjmurphy/ms-common/server/health"
 	"github.com/pkg/errors"
-	"golang.org/x/sync/errgroup"
+	"golang.org/x/net/http2"
 	"google.golang.org/grpc"
 )
 
-var stdSignals = []os.Signal{syscall.SIGINT}
+var stdSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
 
 // Server presents the server service.
 type Server struct {
+	opts   *serverOpts
+	health *health.Health
+
+	// mu guards the servers published by Run, read by Shutdown.
+	mu        sync.Mutex
 	stack     *stack
-	opts      *serverOpts
 	listeners *listeners
+	http      *nett.Server
+
+	// stopping is closed when the shutdown begins, stopped when it completes.
+	stopping chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+	err      error
 }
 
 // Service register the HTTP and GRPC connections.
@@ -28,82 +42,207 @@ type Service interface {
 }
 
 // NewServer creates a gRPC Server and HTTP server on same port with given server options.
+// The server serves the grpc.health.v1.Health service and the /healthz and /readyz HTTP probes,
+// backed by the WithHealthCheck checks.
 func NewServer(addr string, opts ...Option) *Server {
 	serverOpts := defaultServerOpts(addr)
 	for _, opt := range opts {
 		opt(serverOpts)
 	}
-	return &Server{opts: serverOpts}
+	checks := health.New()
+	for _, c := range serverOpts.HealthChecks {
+		checks.Register(c.Name, c.Checker, c.Timeout)
+	}
+	return &Server{
+		opts:     serverOpts,
+		health:   checks,
+		stopping: make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
 }
 
-// Run starts the service.
+// Run starts the service and blocks until the server is shut down, on SIGINT or SIGTERM
+// or by Shutdown.
 func (s *Server) Run(service Service) error {
-	var err error
-	s.listeners, err = newListenerSet(s.opts)
+	lis, err := newListenerSet(s.opts)
 	if err != nil {
 		return errors.Wrap(err, "failed to initialize listeners")
 	}
 
-	s.stack = newStack(s.opts)
-	service.RegisterGRPC(s.stack.grpc)
-	service.RegisterHTTP(s.stack.mux)
+	stack := newStack(s.opts, lis)
+	s.health.RegisterGRPC(stack.grpc)
+	service.RegisterGRPC(stack.grpc)
+	service.RegisterHTTP(stack.mux)
+
+	var handler nett.Handler = stack.mux
+	for i := len(s.opts.HTTPMiddlewares) - 1; i >= 0; i-- {
+		handler = s.opts.HTTPMiddlewares[i](handler)
+	}
+	if lis.certs != nil {
+		handler = identityHandler(handler)
+	}
+	// the probes bypass the middlewares, e.g. the authentication.
+	handler = s.health.Handler(handler)
+	if stack.grpcOverHTTP {
+		handler = grpcHandler(stack.grpc, handler)
+	}
+	httpServer := &nett.Server{Handler: handler}
+	if lis.certs != nil {
+		// the HTTP/2 connections negotiated with ALPN are served by the HTTP/2 server.
+		if err := http2.ConfigureServer(httpServer, &http2.Server{}); err != nil {
+			lis.close()
+			return errors.Wrap(err, "failed to configure HTTP/2")
+		}
+	}
+
+	// the servers are published once built, unless Shutdown was called meanwhile.
+	s.mu.Lock()
+	if s.isStopping() {
+		s.mu.Unlock()
+		lis.close()
+		<-s.stopped
+		return s.err
+	}
+	s.listeners, s.stack, s.http = lis, stack, httpServer
+	s.mu.Unlock()
+	if lis.certs != nil {
+		lis.certs.watch(s.opts.Logger)
+	}
 
-	var g errgroup.Group
-	g.Go(s.waitForShutdown())
-	if err = s.run(); err != nil {
+	go s.waitForShutdown()
+	if err = run(lis, stack, httpServer); err != nil && !s.isStopping() {
+		_ = s.Shutdown()
 		return err
 	}
-	return g.Wait()
+	<-s.stopped
+	return s.err
 }
 
-func (s *Server) run() error {
+func run(lis *listeners, stack *stack, httpServer *nett.Server) error {
 	errChan := make(chan error, 5)
-	if s.listeners.mainListener != nil {
+	if lis.mainListener != nil {
 		go func() {
-			err := s.listeners.mainListener.Serve()
+			err := lis.mainListener.Serve()
 			errChan <- err
 		}()
 	}
 	go func() {
-		var handler nett.Handler = s.stack.mux
-		for i := len(s.opts.HTTPMiddlewares) - 1; i >= 0; i-- {
-			handler = s.opts.HTTPMiddlewares[i](handler)
-		}
-		err := nett.Serve(s.listeners.mux, handler)
-		errChan <- err
-	}()
-	go func() {
-		err := s.stack.grpc.Serve(s.listeners.grpc)
+		err := httpServer.Serve(lis.mux)
 		errChan <- err
 	}()
+	if lis.grpc != nil {
+		go func() {
+			err := stack.grpc.Serve(lis.grpc)
+			errChan <- err
+		}()
+	}
 	return <-errChan
 }
 
-func (s *Server) waitForShutdown() func() error {
-	return func() error {
-		sig := make(chan os.Signal, 1)
-		if len(stdSignals) > 0 {
-			signal.Notify(sig, stdSignals...)
-		}
+func (s *Server) waitForShutdown() {
+	sig := make(chan os.Signal, 1)
+	if len(stdSignals) > 0 {
+		signal.Notify(sig, stdSignals...)
+		defer signal.Stop(sig)
+	}
 
-		// Wait for a shutdown signal.
-		select {
-		case <-sig:
-		}
+	// Wait for a shutdown signal, or for Shutdown.
+	select {
+	case <-sig:
+		_ = s.Shutdown()
+	case <-s.stopping:
+	}
+}
 
-		return s.Shutdown()
+func (s *Server) isStopping() bool {
+	select {
+	case <-s.stopping:
+		return true
+	default:
+		return false
 	}
 }
 
-// Shutdown stops the server gracefully.
+// Health returns the readiness checks of the server, e.g. to register the checks of the dependencies
+// opened after the server creation.
+func (s *Server) Health() *health.Health {
+	return s.health
+}
+
+// Shutdown stops the server gracefully within the grace period: the server is reported not ready,
+// the pre-stop hooks are run and the listeners stop accepting connections, then the HTTP server
+// drains the in-flight requests, the gRPC server does the same, and finally the post-stop hooks
+// are run. The servers still busy at the end of the grace period are stopped abruptly. Shutdown
+// returns the first error encountered.
 func (s *Server) Shutdown() error {
+	s.once.Do(func() {
+		close(s.stopping)
+		s.err = s.shutdown()
+		close(s.stopped)
+	})
+	<-s.stopped
+	return s.err
+}
+
+func (s *Server) shutdown() error {
 	s.opts.Logger.Log("server : shutting down ...")
-	if svc := s.stack; svc != nil {
-		svc.grpc.GracefulStop()
-		err := s.listeners.mux.Close()
-		if err != nil {
-			return err
+	s.health.Shutdown()
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.GracePeriod)
+	defer cancel()
+
+	var errs []error
+	errs = append(errs, s.runHooks(ctx, "pre-stop", s.opts.PreStop)...)
+	s.mu.Lock()
+	stack, lis, httpServer := s.stack, s.listeners, s.http
+	s.mu.Unlock()
+	if stack != nil {
+		lis.close()
+		httpErr := httpServer.Shutdown(ctx)
+		if httpErr != nil {
+			_ = httpServer.Close()
+			errs = append(errs, errors.Wrap(httpErr, "server : failed to drain the HTTP requests"))
+		}
+		if stack.grpcOverHTTP {
+			// the gRPC requests served by the HTTP server are drained with it, and the graceful stop
+			// is not supported by the handlers still running once the HTTP drain failed.
+			stack.grpc.Stop()
+		} else if err := drainGRPC(ctx, stack.grpc); err != nil {
+			errs = append(errs, err)
 		}
 	}
+	errs = append(errs, s.runHooks(ctx, "post-stop", s.opts.PostStop)...)
+	s.opts.Logger.Log("server : shut down")
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
 	return nil
 }
+
+// drainGRPC stops the gRPC server gracefully, or abruptly once the context is done.
+func drainGRPC(ctx context.Context, srv *grpc.Server) error {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		srv.Stop()
+		<-done
+		return errors.Wrap(ctx.Err(), "server : failed to drain the gRPC requests")
+	}
+}
+
+func (s *Server) runHooks(ctx context.Context, stage string, hooks []Hook) []error {
+	var errs []error
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			s.opts.Logger.Log("server : "+stage+" hook failed:", err)
+			errs = append(errs, errors.Wrapf(err, "server : %v hook failed", stage))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"net"
+	nett "net/http"
+	"testing"
+	"time"
+
+	http "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/mauricetjmurphy/ms-common/server/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// slowService serves GET /slow, answering after the delay.
+type slowService struct {
+	delay   time.Duration
+	started chan struct{}
+}
+
+func (s *slowService) RegisterGRPC(*grpc.Server) {}
+
+func (s *slowService) RegisterHTTP(mux *http.ServeMux) {
+	_ = mux.HandlePath(nett.MethodGet, "/slow", func(w nett.ResponseWriter, r *nett.Request, _ map[string]string) {
+		close(s.started)
+		time.Sleep(s.delay)
+		w.WriteHeader(nett.StatusOK)
+	})
+}
+
+func isStarted(started chan struct{}) bool {
+	select {
+	case <-started:
+		return true
+	default:
+		return false
+	}
+}
+
+func freeAddr(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+	return addr
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	cases := []struct {
+		name        string
+		gracePeriod time.Duration
+		wantErr     bool
+		wantStatus  int
+	}{
+		{
+			name:        "Shutdown_DrainsInFlightRequests",
+			gracePeriod: time.Second,
+			wantStatus:  nett.StatusOK,
+		},
+		{
+			name:        "Shutdown_GracePeriodExceeded",
+			gracePeriod: 50 * time.Millisecond,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			var stages []string
+			hook := func(stage string) Hook {
+				return func(ctx context.Context) error {
+					stages = append(stages, stage)
+					return nil
+				}
+			}
+			addr := freeAddr(t)
+			srv := NewServer(addr, WithGracePeriod(tt.gracePeriod), WithPreStop(hook("pre-stop")), WithPostStop(hook("post-stop")))
+			service := &slowService{delay: 300 * time.Millisecond, started: make(chan struct{})}
+			runErr := make(chan error, 1)
+			go func() { runErr <- srv.Run(service) }()
+
+			status := make(chan int, 1)
+			go func() {
+				// retries until the server listens.
+				for i := 0; i < 50; i++ {
+					resp, err := nett.Get("http://" + addr + "/slow")
+					if err == nil {
+						_ = resp.Body.Close()
+						status <- resp.StatusCode
+						return
+					}
+					if isStarted(service.started) {
+						break
+					}
+					time.Sleep(20 * time.Millisecond)
+				}
+				status <- 0
+			}()
+			<-service.started
+
+			//When
+			err := srv.Shutdown()
+
+			//Then
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, []string{"pre-stop", "post-stop"}, stages)
+			assert.Equal(t, err, <-runErr)
+			if tt.wantStatus > 0 {
+				assert.Equal(t, tt.wantStatus, <-status)
+			}
+		})
+	}
+}
+
+func TestServer_Probes(t *testing.T) {
+	//Given
+	addr := freeAddr(t)
+	srv := NewServer(addr,
+		WithHTTPMiddlewares(func(nett.Handler) nett.Handler {
+			return nett.HandlerFunc(func(w nett.ResponseWriter, r *nett.Request) {
+				w.WriteHeader(nett.StatusUnauthorized)
+			})
+		}),
+		WithHealthCheck("db", health.CheckerFunc(func(context.Context) error { return nil }), 0),
+	)
+	runErr := make(chan error, 1)
+	go func() { runErr <- srv.Run(&slowService{started: make(chan struct{})}) }()
+
+	//When
+	var readiness, liveness *nett.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		if readiness, err = nett.Get("http://" + addr + health.ReadinessPath); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	_ = readiness.Body.Close()
+	liveness, err = nett.Get("http://" + addr + health.LivenessPath)
+	require.NoError(t, err)
+	_ = liveness.Body.Close()
+
+	//Then
+	assert.Equal(t, nett.StatusOK, readiness.StatusCode)
+	assert.Equal(t, nett.StatusOK, liveness.StatusCode)
+	assert.NoError(t, srv.Shutdown())
+	assert.NoError(t, <-runErr)
+	assert.Equal(t, health.StatusShuttingDown, srv.Health().Check(context.Background()).Status)
+}
+
+func TestServer_Shutdown_StopsAccepting(t *testing.T) {
+	//Given
+	addr := freeAddr(t)
+	srv := NewServer(addr, WithGracePeriod(time.Second))
+	service := &slowService{delay: 500 * time.Millisecond, started: make(chan struct{})}
+	runErr := make(chan error, 1)
+	go func() { runErr <- srv.Run(service) }()
+	status := make(chan int, 1)
+	go func() {
+		for i := 0; i < 50; i++ {
+			if resp, err := nett.Get("http://" + addr + "/slow"); err == nil {
+				_ = resp.Body.Close()
+				status <- resp.StatusCode
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		status <- 0
+	}()
+	<-service.started
+
+	//When
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- srv.Shutdown() }()
+	var dialErr error
+	for i := 0; i < 20 && dialErr == nil; i++ {
+		var conn net.Conn
+		if conn, dialErr = net.Dial("tcp", addr); dialErr == nil {
+			_ = conn.Close()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	//Then
+	assert.Error(t, dialErr, "connections accepted while draining")
+	assert.Equal(t, nett.StatusOK, <-status)
+	assert.NoError(t, <-shutdownErr)
+	assert.NoError(t, <-runErr)
+}
+
+func TestServer_Shutdown_BeforeRun(t *testing.T) {
+	//Given
+	srv := NewServer(freeAddr(t))
+	require.NoError(t, srv.Shutdown())
+
+	//When
+	runErr := make(chan error, 1)
+	go func() { runErr <- srv.Run(&slowService{started: make(chan struct{})}) }()
+
+	//Then
+	select {
+	case err := <-runErr:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run served after Shutdown")
+	}
+}
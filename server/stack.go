@@ -1,13 +1,16 @@
 package server
 
 import (
+	"crypto/tls"
 	"net"
+	"sync"
 	"time"
 
 	http "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/pkg/errors"
 	"github.com/soheilhy/cmux"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 const (
@@ -18,40 +21,106 @@ const (
 type stack struct {
 	grpc *grpc.Server
 	mux  *http.ServeMux
+	// grpcOverHTTP is set when the gRPC requests are served by the HTTP server, see grpcHandler.
+	grpcOverHTTP bool
 }
 
 type listeners struct {
 	mainListener cmux.CMux
-	grpc         net.Listener
-	mux          net.Listener
+	// grpc is nil when the gRPC requests are served by the HTTP server.
+	grpc net.Listener
+	mux  net.Listener
+	// tcp are the listeners of the server addresses, the root of the cmux listeners on a shared one.
+	tcp []net.Listener
+	// certs are the TLS certificates of the listeners, nil when serving plaintext.
+	certs *certReloader
 }
 
-func newStack(opts *serverOpts) *stack {
+func newStack(opts *serverOpts, lis *listeners) *stack {
+	grpcOpts := opts.GRPCOpts
+	if lis.certs != nil && lis.grpc != nil {
+		grpcOpts = append([]grpc.ServerOption{grpc.Creds(credentials.NewTLS(lis.certs.config()))}, grpcOpts...)
+	}
 	return &stack{
-		grpc: grpc.NewServer(opts.GRPCOpts...),
-		mux:  http.NewServeMux(opts.HTTPMuxOpts...),
+		grpc:         grpc.NewServer(grpcOpts...),
+		mux:          http.NewServeMux(opts.HTTPMuxOpts...),
+		grpcOverHTTP: lis.grpc == nil,
 	}
 }
 
 func newListenerSet(opts *serverOpts) (*listeners, error) {
 	lis := &listeners{}
 	var err error
-	lis.grpc, err = newListener(opts.GRPCAddr)
+	lis.grpc, err = lis.listen(opts.GRPCAddr)
 	if err != nil {
 		return nil, errors.Wrap(err, "couldn't create main listener")
 	}
-	if opts.GRPCAddr == opts.HTTPAddr {
+	if opts.TLS != nil {
+		if lis.certs, err = newCertReloader(opts.TLS); err != nil {
+			_ = lis.grpc.Close()
+			return nil, err
+		}
+	}
+	switch {
+	case opts.GRPCAddr == opts.HTTPAddr && lis.certs != nil:
+		// HTTP/2 is negotiated with ALPN, the gRPC requests being told apart from the gateway ones
+		// by the HTTP server, see grpcHandler.
+		lis.mux, lis.grpc = tls.NewListener(lis.grpc, lis.certs.config()), nil
+	case opts.GRPCAddr == opts.HTTPAddr:
 		mux := cmux.New(lis.grpc)
 		lis.grpc = mux.Match(cmux.HTTP2())
 		lis.mux = mux.Match(cmux.Any())
 		lis.mainListener = mux
-	} else {
-		lis.mux, err = newListener(opts.HTTPAddr)
+	default:
+		if lis.mux, err = lis.listen(opts.HTTPAddr); err != nil {
+			_ = lis.grpc.Close()
+			return nil, errors.Wrap(err, "couldn't create HTTP listener")
+		}
+		if lis.certs != nil {
+			// the gRPC server terminates its own TLS, see newStack.
+			lis.mux = tls.NewListener(lis.mux, lis.certs.config())
+		}
 	}
+	return lis, nil
+}
+
+// listen starts the TCP listener on addr, closed by close.
+func (lis *listeners) listen(addr string) (net.Listener, error) {
+	l, err := newListener(addr)
 	if err != nil {
-		return nil, errors.Wrap(err, "couldn't create HTTP listener")
+		return nil, err
 	}
-	return lis, nil
+	tcp := &onceCloseListener{Listener: l}
+	lis.tcp = append(lis.tcp, tcp)
+	return tcp, nil
+}
+
+// close stops accepting the connections, the accepted ones being left to the servers to drain.
+func (lis *listeners) close() {
+	for _, l := range lis.tcp {
+		_ = l.Close()
+	}
+	if lis.mainListener != nil {
+		lis.mainListener.Close()
+	}
+	if lis.certs != nil {
+		lis.certs.close()
+	}
+}
+
+// onceCloseListener closes the listener once: the listeners are closed first on shutdown,
+// then again by the servers stopping.
+type onceCloseListener struct {
+	net.Listener
+	once sync.Once
+	err  error
+}
+
+func (l *onceCloseListener) Close() error {
+	l.once.Do(func() {
+		l.err = l.Listener.Close()
+	})
+	return l.err
 }
 
 // newListener start net.Listener on an addr. Keeps retrying if address is already in use.
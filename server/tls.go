@@ -0,0 +1,239 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// DefaultCertReloadInterval is the default interval the certificate files are checked for changes.
+const DefaultCertReloadInterval = time.Minute
+
+// TLSConfig presents the TLS settings of the server listeners, gRPC and HTTP being served over TLS
+// on the same port.
+type TLSConfig struct {
+	// CertFile and KeyFile are the PEM encoded server certificate and private key.
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is the PEM encoded CA bundle verifying the client certificates, enabling mTLS.
+	ClientCAFile string
+	// ClientAuth is the client certificate policy, defaults to tls.RequireAndVerifyClientCert
+	// when ClientCAFile is set.
+	ClientAuth tls.ClientAuthType
+	// MinVersion is the minimum TLS version, defaults to TLS 1.2.
+	MinVersion uint16
+	// ReloadInterval is the interval the certificate files are checked for changes, defaults to
+	// DefaultCertReloadInterval, negative disables the reload.
+	ReloadInterval time.Duration
+}
+
+// Identity is the identity of the verified client certificate.
+type Identity struct {
+	CommonName     string
+	DNSNames       []string
+	URIs           []string
+	EmailAddresses []string
+	// Certificate is the verified client certificate.
+	Certificate *x509.Certificate
+}
+
+type identityKey struct{}
+
+// ClientIdentity returns the identity of the verified client certificate of the gRPC or HTTP request.
+func ClientIdentity(ctx context.Context) (*Identity, bool) {
+	if identity, ok := ctx.Value(identityKey{}).(*Identity); ok {
+		return identity, true
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			return identityOf(info.State)
+		}
+	}
+	return nil, false
+}
+
+// identityOf returns the identity of the verified client certificate of the connection.
+func identityOf(state tls.ConnectionState) (*Identity, bool) {
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	cert := state.VerifiedChains[0][0]
+	identity := &Identity{
+		CommonName:     cert.Subject.CommonName,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		Certificate:    cert,
+	}
+	for _, uri := range cert.URIs {
+		identity.URIs = append(identity.URIs, uri.String())
+	}
+	return identity, true
+}
+
+// identityHandler stores the client identity of the TLS connection in the request context.
+func identityHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			if identity, ok := identityOf(*r.TLS); ok {
+				r = r.WithContext(context.WithValue(r.Context(), identityKey{}, identity))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// grpcHandler serves the gRPC requests of the HTTP/2 connections with the gRPC server, and the
+// other requests with next.
+func grpcHandler(grpcServer *grpc.Server, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// certReloader serves the certificates read from the files, reloading them when the files change.
+// A failed reload keeps serving the previous certificates.
+type certReloader struct {
+	cfg *TLSConfig
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes []time.Time
+
+	stop chan struct{}
+	once sync.Once
+}
+
+func newCertReloader(cfg *TLSConfig) (*certReloader, error) {
+	r := &certReloader{cfg: cfg, stop: make(chan struct{})}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// files returns the certificate files.
+func (r *certReloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+// reload reads the certificate files when they changed since the last read, returning whether they did.
+func (r *certReloader) reload() (bool, error) {
+	var modTimes []time.Time
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, errors.Wrapf(err, "server : failed to read the TLS file %v", file)
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+	r.mu.RLock()
+	changed := len(r.modTimes) != len(modTimes)
+	for i := 0; !changed && i < len(modTimes); i++ {
+		changed = !modTimes[i].Equal(r.modTimes[i])
+	}
+	r.mu.RUnlock()
+	if !changed {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return false, errors.Wrap(err, "server : failed to load the TLS certificate")
+	}
+	var clientCA *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return false, errors.Wrap(err, "server : failed to read the client CA")
+		}
+		clientCA = x509.NewCertPool()
+		if !clientCA.AppendCertsFromPEM(pem) {
+			return false, errors.Errorf("server : no certificate found in the client CA %v", r.cfg.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.clientCA, r.modTimes = &cert, clientCA, modTimes
+	r.mu.Unlock()
+	return true, nil
+}
+
+// watch reloads the certificates on every reload interval until close.
+func (r *certReloader) watch(logger Logger) {
+	interval := r.cfg.ReloadInterval
+	if interval == 0 {
+		interval = DefaultCertReloadInterval
+	}
+	if interval < 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				reloaded, err := r.reload()
+				if err != nil {
+					logger.Log("server : failed to reload the TLS certificates:", err)
+				} else if reloaded {
+					logger.Log("server : TLS certificates reloaded")
+				}
+			}
+		}
+	}()
+}
+
+func (r *certReloader) close() {
+	r.once.Do(func() {
+		close(r.stop)
+	})
+}
+
+// config returns the TLS configuration of the listeners, resolving the current certificates per handshake.
+func (r *certReloader) config() *tls.Config {
+	minVersion := r.cfg.MinVersion
+	if minVersion == 0 {
+		minVersion = tls.VersionTLS12
+	}
+	clientAuth := r.cfg.ClientAuth
+	if clientAuth == tls.NoClientCert && r.cfg.ClientCAFile != "" {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+	return &tls.Config{
+		MinVersion: minVersion,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return &tls.Config{
+				MinVersion:   minVersion,
+				NextProtos:   []string{"h2", "http/1.1"},
+				Certificates: []tls.Certificate{*r.cert},
+				ClientAuth:   clientAuth,
+				ClientCAs:    r.clientCA,
+			}, nil
+		},
+	}
+}
@@ -0,0 +1,367 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	nett "net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	http "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/mauricetjmurphy/ms-common/server/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// testCert is a certificate and its key, signed by parent or self-signed.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+	tls  tls.Certificate
+}
+
+func newTestCert(t *testing.T, cn string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key, pem: certPEM, tls: pair}
+}
+
+// write writes the certificate and its key in dir, returning the files.
+func (c *testCert) write(t *testing.T, dir string) (string, string) {
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, c.pem, 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+// identityService serves GET /identity, answering the common name of the client identity.
+type identityService struct{}
+
+func (identityService) RegisterGRPC(*grpc.Server) {}
+
+func (identityService) RegisterHTTP(mux *http.ServeMux) {
+	_ = mux.HandlePath(nett.MethodGet, "/identity", func(w nett.ResponseWriter, r *nett.Request, _ map[string]string) {
+		identity, ok := ClientIdentity(r.Context())
+		if !ok {
+			w.WriteHeader(nett.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(identity.CommonName))
+	})
+}
+
+func TestServer_TLS(t *testing.T) {
+	//Given
+	ca := newTestCert(t, "ca", nil)
+	serverCert := newTestCert(t, "server", ca)
+	clientCert := newTestCert(t, "client", ca)
+	dir := t.TempDir()
+	certFile, keyFile := serverCert.write(t, dir)
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0600))
+
+	var grpcIdentity *Identity
+	addr := freeAddr(t)
+	srv := NewServer(addr,
+		WithTLS(TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile}),
+		WithGRPCOpts(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			grpcIdentity, _ = ClientIdentity(ctx)
+			return handler(ctx, req)
+		})),
+	)
+	runErr := make(chan error, 1)
+	go func() { runErr <- srv.Run(identityService{}) }()
+	defer func() {
+		assert.NoError(t, srv.Shutdown())
+		assert.NoError(t, <-runErr)
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientTLS := func(certs ...tls.Certificate) *tls.Config {
+		return &tls.Config{RootCAs: roots, Certificates: certs}
+	}
+	cases := []struct {
+		name      string
+		transport nett.RoundTripper
+		wantErr   bool
+		wantProto int
+	}{
+		{
+			name:      "HTTP1_WithClientCert",
+			transport: &nett.Transport{TLSClientConfig: clientTLS(clientCert.tls)},
+			wantProto: 1,
+		},
+		{
+			name:      "HTTP2_WithClientCert",
+			transport: &http2.Transport{TLSClientConfig: clientTLS(clientCert.tls)},
+			wantProto: 2,
+		},
+		{
+			name:      "HTTP1_WithoutClientCert",
+			transport: &nett.Transport{TLSClientConfig: clientTLS()},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//When
+			client := &nett.Client{Transport: tt.transport, Timeout: 5 * time.Second}
+			var resp *nett.Response
+			var err error
+			for i := 0; i < 50; i++ {
+				if resp, err = client.Get("https://" + addr + "/identity"); err == nil || !isRefused(err) {
+					break
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+
+			//Then
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body := make([]byte, 64)
+			n, _ := resp.Body.Read(body)
+			assert.Equal(t, nett.StatusOK, resp.StatusCode)
+			assert.Equal(t, tt.wantProto, resp.ProtoMajor)
+			assert.Equal(t, "client", string(body[:n]))
+		})
+	}
+
+	t.Run("GRPC_WithClientCert", func(t *testing.T) {
+		//When
+		conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(credentials.NewTLS(clientTLS(clientCert.tls))))
+		require.NoError(t, err)
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+
+		//Then
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+		require.NotNil(t, grpcIdentity)
+		assert.Equal(t, "client", grpcIdentity.CommonName)
+	})
+}
+
+func isRefused(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func TestCertReloader_Reload(t *testing.T) {
+	//Given
+	ca := newTestCert(t, "ca", nil)
+	first, second := newTestCert(t, "first", ca), newTestCert(t, "second", ca)
+	dir := t.TempDir()
+	certFile, keyFile := first.write(t, dir)
+	reloader, err := newCertReloader(&TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	unchanged, err := reloader.reload()
+	require.NoError(t, err)
+
+	//When
+	second.write(t, dir)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+	reloaded, err := reloader.reload()
+
+	//Then
+	require.NoError(t, err)
+	assert.False(t, unchanged)
+	assert.True(t, reloaded)
+	cfg, err := reloader.config().GetConfigForClient(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, second.tls.Certificate, cfg.Certificates[0].Certificate)
+	assert.Equal(t, tls.NoClientCert, cfg.ClientAuth)
+}
+
+// tlsFiles writes the certificates of a server verifying the client certificates signed by the
+// returned CA, returning the server TLS configuration and the client one.
+func tlsFiles(t *testing.T) (TLSConfig, *tls.Config) {
+	ca := newTestCert(t, "ca", nil)
+	serverCert := newTestCert(t, "server", ca)
+	clientCert := newTestCert(t, "client", ca)
+	dir := t.TempDir()
+	certFile, keyFile := serverCert.write(t, dir)
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0600))
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	return TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile},
+		&tls.Config{RootCAs: roots, Certificates: []tls.Certificate{clientCert.tls}}
+}
+
+func TestServer_TLS_SplitPorts(t *testing.T) {
+	//Given
+	serverTLS, clientTLS := tlsFiles(t)
+	var grpcIdentity *Identity
+	grpcAddr, httpAddr := freeAddr(t), freeAddr(t)
+	srv := NewServer(grpcAddr,
+		WithHTTPAddr(httpAddr),
+		WithTLS(serverTLS),
+		WithGRPCOpts(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			grpcIdentity, _ = ClientIdentity(ctx)
+			return handler(ctx, req)
+		})),
+	)
+	runErr := make(chan error, 1)
+	go func() { runErr <- srv.Run(identityService{}) }()
+	defer func() {
+		assert.NoError(t, srv.Shutdown())
+		assert.NoError(t, <-runErr)
+	}()
+
+	//When
+	client := &nett.Client{Transport: &http2.Transport{TLSClientConfig: clientTLS}, Timeout: 5 * time.Second}
+	var resp *nett.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		if resp, err = client.Get("https://" + httpAddr + "/identity"); err == nil || !isRefused(err) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	conn, err := grpc.Dial(grpcAddr, grpc.WithTransportCredentials(credentials.NewTLS(clientTLS)))
+	require.NoError(t, err)
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	check, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+
+	//Then
+	assert.Equal(t, nett.StatusOK, resp.StatusCode)
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check.Status)
+	require.NotNil(t, grpcIdentity)
+	assert.Equal(t, "client", grpcIdentity.CommonName)
+}
+
+func TestServer_TLS_Shutdown(t *testing.T) {
+	cases := []struct {
+		name string
+		call func(addr string, clientTLS *tls.Config) error
+	}{
+		{
+			name: "Shutdown_DrainsHTTP2Requests",
+			call: func(addr string, clientTLS *tls.Config) error {
+				client := &nett.Client{Transport: &http2.Transport{TLSClientConfig: clientTLS}}
+				resp, err := client.Get("https://" + addr + "/slow")
+				if err != nil {
+					return err
+				}
+				_ = resp.Body.Close()
+				if resp.StatusCode != nett.StatusOK {
+					return errors.New(resp.Status)
+				}
+				return nil
+			},
+		},
+		{
+			name: "Shutdown_DrainsGRPCRequests",
+			call: func(addr string, clientTLS *tls.Config) error {
+				conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(credentials.NewTLS(clientTLS)))
+				if err != nil {
+					return err
+				}
+				defer conn.Close()
+				_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+				return err
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			//Given
+			serverTLS, clientTLS := tlsFiles(t)
+			addr := freeAddr(t)
+			service := &slowService{delay: 300 * time.Millisecond, started: make(chan struct{})}
+			srv := NewServer(addr,
+				WithTLS(serverTLS),
+				WithGracePeriod(5*time.Second),
+				WithHealthCheck("slow", health.CheckerFunc(func(context.Context) error {
+					if !isStarted(service.started) {
+						close(service.started)
+					}
+					time.Sleep(service.delay)
+					return nil
+				}), time.Second),
+			)
+			runErr := make(chan error, 1)
+			go func() { runErr <- srv.Run(service) }()
+			callErr := make(chan error, 1)
+			go func() {
+				var err error
+				for i := 0; i < 50; i++ {
+					if err = tt.call(addr, clientTLS); err == nil || !isRefused(err) {
+						break
+					}
+					time.Sleep(20 * time.Millisecond)
+				}
+				callErr <- err
+			}()
+			<-service.started
+
+			//When
+			err := srv.Shutdown()
+
+			//Then
+			assert.NoError(t, err)
+			assert.NoError(t, <-callErr)
+			assert.NoError(t, <-runErr)
+		})
+	}
+}